@@ -1,15 +1,29 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"net/http"
+	"strconv"
+	"syscall"
+	"time"
 
 
 	"github.com/vvetta/electoral_system/internal/adapters/http"
 	"github.com/vvetta/electoral_system/internal/adapters/logger"
 	motoparser "github.com/vvetta/electoral_system/internal/adapters/moto_parser"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/drift_history_repo"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/job_repo"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/lock_repo"
 	"github.com/vvetta/electoral_system/internal/adapters/repository/moto_repo"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/salon_repo"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/sync_run_repo"
+	"github.com/vvetta/electoral_system/internal/domain"
 	"github.com/vvetta/electoral_system/internal/usecase"
 
 	"github.com/joho/godotenv"
@@ -17,8 +31,8 @@ import (
 	"gorm.io/gorm"
 )
 
-var (
-	url = "https://mr-moto.ru/catalog/mototsikly/"
+const (
+	defaultSourcesConfigPath = "configs/sources.json"
 )
 
 func main() {
@@ -35,14 +49,87 @@ func main() {
 	lg := logger.NewLogger()
 
 	motoRepo := motorepo.NewMotoRepo(db, lg)
-	motoParser := motoparser.NewMotoParser(url, "page-card__col", 100)
+	salonRepo := salonrepo.NewSalonRepo(db, lg)
+	jobRepo := jobrepo.NewJobRepo(db, lg)
+	lockRepo := lockrepo.NewLockRepo(db, lg)
+	syncRunRepo := syncrunrepo.NewSyncRunRepo(db, lg)
+	driftHistoryRepo := drifthistoryrepo.NewDriftHistoryRepo(db, lg)
 
-	motoSVC := usecase.NewMotoService(lg, motoRepo, motoParser)
+	sourcesConfigPath := os.Getenv("SOURCES_CONFIG")
+	if sourcesConfigPath == "" {
+		sourcesConfigPath = defaultSourcesConfigPath
+	}
+
+	sourcesConfig, err := motoparser.LoadSourcesConfig(sourcesConfigPath)
+	if err != nil {
+		log.Fatalf("failed to load sources config: %v", err)
+	}
+
+	sources := usecase.NewSourceRegistry()
+	if err := motoparser.RegisterSources(sources, sourcesConfig); err != nil {
+		log.Fatalf("failed to register sources: %v", err)
+	}
+
+	// Что делать с обходом, в котором разметка сайта "уплыла": refuse (по умолчанию) или flag
+	drift := domain.DefaultDriftPolicy()
+	if action := os.Getenv("DRIFT_ACTION"); action != "" {
+		if action != domain.DriftActionRefuse && action != domain.DriftActionFlag {
+			log.Fatalf("invalid DRIFT_ACTION %q: expected refuse or flag", action)
+		}
+		drift.Action = action
+	}
+
+	motoSVC := usecase.NewMotoService(lg, motoRepo, salonRepo, sources, drift, driftHistoryRepo, lockRepo, syncRunRepo)
+	salonSVC := usecase.NewSalonService(lg, salonRepo, motoRepo)
+
+	jobSVC, err := usecase.NewJobService(lg, motoSVC, jobRepo, lockRepo)
+	if err != nil {
+		log.Fatalf("failed to create job service: %v", err)
+	}
+
+	// Справочник салонов с адресами и контактами необязателен:
+	// без него салоны создаются парсером только с названием
+	if salonsConfigPath := os.Getenv("SALONS_CONFIG"); salonsConfigPath != "" {
+		salons, err := loadSalonsConfig(salonsConfigPath)
+		if err != nil {
+			log.Fatalf("failed to load salons config: %v", err)
+		}
+		if err := salonSVC.ImportSalons(context.Background(), salons); err != nil {
+			log.Fatalf("failed to import salons: %v", err)
+		}
+	}
 	
-	srv := httpserver.NewServer(motoSVC, lg)
-	if err := http.ListenAndServe(":8080", srv); err != nil {
+	schedulePolicy, scheduleEnabled, err := loadSchedulePolicy()
+	if err != nil {
+		log.Fatalf("failed to load sync schedule: %v", err)
+	}
+
+	scheduler, err := usecase.NewSyncScheduler(lg, motoSVC, schedulePolicy, scheduleEnabled)
+	if err != nil {
+		log.Fatalf("failed to create sync scheduler: %v", err)
+	}
+	// По сигналу останавливаем сервер и задачи, чтобы отпустить блокировку владельца
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go scheduler.Run(ctx)
+
+	srv := &http.Server{
+		Addr: ":8080",
+		Handler: httpserver.NewServer(motoSVC, salonSVC, jobSVC, scheduler, lg),
+	}
+	go func() {
+		<-ctx.Done()
+		if err := srv.Shutdown(context.Background()); err != nil {
+			lg.Error("failed to shutdown http server", "err", err)
+		}
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		jobSVC.Close()
 		log.Fatal(err)
 	}
+	jobSVC.Close()
 }
 
 func getDSN() string {
@@ -55,3 +142,86 @@ func getDSN() string {
 	dsn := "postgres://" + DB_USER + ":" + DB_PASS + "@" + DB_HOST + ":" + DB_PORT + "/" + DB_NAME + "?sslmode=disable"
 	return dsn
 }
+
+/*
+loadSchedulePolicy читает расписание обхода каталога из окружения.
+По умолчанию планировщик выключен, его можно включить через
+SYNC_ENABLED=true или POST /api/v1/scheduler/enable.
+*/
+func loadSchedulePolicy() (domain.SchedulePolicy, bool, error) {
+	policy := domain.DefaultSchedulePolicy()
+
+	var enabled bool
+	if v := os.Getenv("SYNC_ENABLED"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return policy, false, fmt.Errorf("invalid SYNC_ENABLED %q: %w", v, err)
+		}
+		enabled = parsed
+	}
+
+	if v := os.Getenv("SYNC_CRON"); v != "" {
+		policy.Cron = v
+	}
+
+	durations := []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"SYNC_JITTER", &policy.Jitter},
+		{"SYNC_BACKOFF_MIN", &policy.BackoffMin},
+		{"SYNC_BACKOFF_MAX", &policy.BackoffMax},
+	}
+	for _, d := range durations {
+		v := os.Getenv(d.env)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed < 0 {
+			return policy, false, fmt.Errorf("invalid %s %q: expected a duration like 10m", d.env, v)
+		}
+		*d.dst = parsed
+	}
+
+	return policy, enabled, nil
+}
+
+type salonConfig struct {
+	Name string `json:"name"`
+	City string `json:"city"`
+	Address string `json:"address"`
+	Latitude *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Phone string `json:"phone"`
+	URL string `json:"url"`
+}
+
+func loadSalonsConfig(path string) ([]domain.Salon, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg struct {
+		Salons []salonConfig `json:"salons"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	salons := make([]domain.Salon, 0, len(cfg.Salons))
+	for _, s := range cfg.Salons {
+		salons = append(salons, domain.Salon{
+			Name: s.Name,
+			City: s.City,
+			Address: s.Address,
+			Latitude: s.Latitude,
+			Longitude: s.Longitude,
+			Phone: s.Phone,
+			URL: s.URL,
+		})
+	}
+
+	return salons, nil
+}
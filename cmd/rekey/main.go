@@ -0,0 +1,48 @@
+/*
+rekey - разовый пересчет сохраненных ключей объявлений после смены
+формулы domain.Moto.ListingKey (миграция 000020). Запускать после
+make migrate-up и до следующего обхода, иначе обход заведет объявления
+заново под новыми ключами.
+*/
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/moto_repo"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	_ = godotenv.Load(".env")
+
+	db, err := gorm.Open(postgres.Open(getDSN()), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+
+	motoRepo := motorepo.NewMotoRepo(db, logger.NewLogger())
+
+	stats, err := motoRepo.RekeyListings(context.Background())
+	if err != nil {
+		log.Fatalf("failed to rekey listings: %v", err)
+	}
+
+	log.Printf("listings rekeyed: %d, duplicates merged: %d", stats.Rekeyed, stats.Merged)
+}
+
+func getDSN() string {
+	DB_USER := os.Getenv("DB_USER")
+	DB_PASS := os.Getenv("DB_PASS")
+	DB_HOST := os.Getenv("DB_HOST")
+	DB_PORT := os.Getenv("DB_PORT")
+	DB_NAME := os.Getenv("DB_NAME")
+
+	return "postgres://" + DB_USER + ":" + DB_PASS + "@" + DB_HOST + ":" + DB_PORT + "/" + DB_NAME + "?sslmode=disable"
+}
@@ -9,9 +9,66 @@ type RequestGetMotos struct {
     YearOption       int    `json:"year_option"`
     MileageOption    int    `json:"mileage_option"`
     PriceMax *int64  `json:"price_max"`
-    MotoType string  `json:"moto_type"`
+    MotoType string  `json:"moto_type"` // id класса из /api/v1/motos/classes, "-" - любой
+    Powertrain string `json:"powertrain"` // "ice", "electric" или пусто
+    Brand string `json:"brand"` // каноничная марка, например "Yamaha"
+    Model string `json:"model"`
+    City string `json:"city"` // город салона
+    IncludeInactive bool `json:"include_inactive"` // показать и снятые с продажи
+    PriceDropped bool `json:"price_dropped"` // только подешевевшие за последние две недели
 }
 
 type ResponseGetMotos struct {
 	Motos []domain.Moto `json:"motos"`
 }
+
+type MotoClass struct {
+	ID string `json:"id"`
+	Title string `json:"title"`
+	Description string `json:"description"`
+}
+
+type ResponseGetMotoClasses struct {
+	Classes []MotoClass `json:"classes"`
+}
+
+type ResponseJob struct {
+	Job domain.ParseJob `json:"job"`
+}
+
+type ImportRowError struct {
+	Row int `json:"row"` // строка файла (у csv заголовок - строка 1) или номер элемента json-массива
+	Error string `json:"error"`
+}
+
+type ResponseImportMotos struct {
+	Source string `json:"source"`
+	Rows int `json:"rows"`
+	Imported int `json:"imported"`
+	Rejected int `json:"rejected"`
+	Failed int `json:"failed"` // не записались в базу
+	Errors []ImportRowError `json:"errors"` // первые domain.MaxRejectedCardsInReport строк
+	UnknownColumns []string `json:"unknown_columns"`
+}
+
+type ResponsePriceHistory struct {
+	MotoID uint `json:"moto_id"`
+	History []domain.PricePoint `json:"history"` // от старых записей к новым
+}
+
+type ResponseHealth struct {
+	Status string `json:"status"` // худший статус из sources
+	Sources []domain.SourceHealth `json:"sources"`
+}
+
+type ResponseScheduler struct {
+	Scheduler domain.SchedulerStatus `json:"scheduler"`
+}
+
+type ResponseSyncRuns struct {
+	Runs []domain.SyncRun `json:"runs"` // новые записи первыми
+}
+
+type ResponseParseReports struct {
+	Reports []domain.ParseReport `json:"reports"`
+}
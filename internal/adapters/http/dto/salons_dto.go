@@ -0,0 +1,14 @@
+package dto
+
+import (
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+type ResponseGetSalons struct {
+	Salons []domain.Salon `json:"salons"`
+}
+
+type ResponseGetSalonMotos struct {
+	Salon domain.Salon `json:"salon"`
+	Motos []domain.Moto `json:"motos"`
+}
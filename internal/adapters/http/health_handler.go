@@ -0,0 +1,64 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/vvetta/electoral_system/internal/adapters/http/dto"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+type HealthHandler struct {
+	svc usecase.MotoService
+	lg usecase.Logger
+}
+
+func NewHealthHandler(
+	svc usecase.MotoService,
+	lg usecase.Logger,
+) *HealthHandler {
+	return &HealthHandler{
+		svc: svc,
+		lg: lg,
+	}
+}
+
+func (h *HealthHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/health", h.handleHealth)
+}
+
+/*
+handleHealth отдает здоровье источников по последним обходам.
+Общий статус - худший из источников; при failed отвечаем 503,
+чтобы мониторинг мог смотреть только на код ответа.
+*/
+func (h *HealthHandler) handleHealth(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	response := dto.ResponseHealth{
+		Status: domain.HealthOK,
+		Sources: h.svc.Health(r.Context()),
+	}
+
+	for _, source := range response.Sources {
+		if healthRank[source.Status] > healthRank[response.Status] {
+			response.Status = source.Status
+		}
+	}
+
+	status := http.StatusOK
+	if response.Status == domain.HealthFailed {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, response)
+}
+
+// Чем больше, тем хуже
+var healthRank = map[string]int{
+	domain.HealthOK: 0,
+	domain.HealthUnknown: 1,
+	domain.HealthDegraded: 2,
+	domain.HealthFailed: 3,
+}
@@ -0,0 +1,75 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/vvetta/electoral_system/internal/adapters/http/dto"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+type JobsHandler struct {
+	svc usecase.JobService
+	lg usecase.Logger
+}
+
+func NewJobsHandler(
+	svc usecase.JobService,
+	lg usecase.Logger,
+) *JobsHandler {
+	return &JobsHandler{
+		svc: svc,
+		lg: lg,
+	}
+}
+
+func (h *JobsHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/jobs/{id}", h.handleGetJob)
+	mux.HandleFunc("DELETE /api/v1/jobs/{id}", h.handleCancelJob)
+}
+
+func (h *JobsHandler) handleGetJob(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	job, err := h.svc.GetJob(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, domain.RecordNotFound) {
+			writeError(w, http.StatusNotFound, errorResponse{Error: "job not found"})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errorResponse{})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dto.ResponseJob{Job: job})
+}
+
+/*
+handleCancelJob просит обход остановиться и сразу отвечает 202:
+статус canceled задача получит, когда обход действительно остановится.
+Отменить уже завершенную задачу нельзя - 409.
+*/
+func (h *JobsHandler) handleCancelJob(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	h.lg.Debug("JobsHandler_CancelJob: Start!")
+
+	job, err := h.svc.CancelJob(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, domain.RecordNotFound) {
+			writeError(w, http.StatusNotFound, errorResponse{Error: "job not found"})
+			return
+		}
+		if errors.Is(err, domain.JobFinished) {
+			writeError(w, http.StatusConflict, errorResponse{Error: "job already finished", Message: job.Status})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errorResponse{})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, dto.ResponseJob{Job: job})
+}
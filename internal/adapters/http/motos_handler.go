@@ -3,7 +3,11 @@ package httpserver
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/vvetta/electoral_system/internal/adapters/http/dto"
 	"github.com/vvetta/electoral_system/internal/domain"
@@ -12,15 +16,18 @@ import (
 
 type MotosHandler struct {
 	svc usecase.MotoService
+	jobs usecase.JobService
 	lg usecase.Logger
 }
 
 func NewMotosHandler(
 	svc usecase.MotoService, 
+	jobs usecase.JobService,
 	lg usecase.Logger,
 ) *MotosHandler {
 	return &MotosHandler{
 		svc: svc,
+		jobs: jobs,
 		lg: lg,
 	}
 }
@@ -28,6 +35,153 @@ func NewMotosHandler(
 func (h *MotosHandler) Register(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/v1/motos/getByFilter", h.handleGetMotos)
 	mux.HandleFunc("POST /api/v1/motos/parseAndUpdate", h.handleParseAndUpdate)
+	mux.HandleFunc("GET /api/v1/motos/parseReport", h.handleParseReport)
+	mux.HandleFunc("GET /api/v1/motos/classes", h.handleGetClasses)
+	mux.HandleFunc("POST /api/v1/motos/import", h.handleImport)
+	mux.HandleFunc("GET /api/v1/motos/{id}/price-history", h.handlePriceHistory)
+}
+
+func (h *MotosHandler) handlePriceHistory(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	h.lg.Debug("MotosHandler_PriceHistory: Start!")
+
+	motoID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errorResponse{
+			Error: "invalid path",
+			Fields: map[string]string{"id": "must be a positive integer"},
+		})
+		return
+	}
+
+	history, err := h.svc.GetPriceHistory(r.Context(), uint(motoID))
+	if err != nil {
+		if errors.Is(err, domain.RecordNotFound) {
+			writeError(w, http.StatusNotFound, errorResponse{Error: "moto not found"})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errorResponse{})
+		return
+	}
+
+	response := dto.ResponsePriceHistory{
+		MotoID: uint(motoID),
+		History: history,
+	}
+
+	h.lg.Debug("MotosHandler_PriceHistory: End!", "id", motoID, "points", len(history))
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Максимальный размер загружаемой выгрузки
+const maxImportFileSize = 32 << 20
+
+/*
+handleImport принимает multipart-форму с полями source (имя файлового источника
+из конфига) и file (CSV, JSON или NDJSON) и прогоняет файл через обычный
+конвейер парсинга. Ошибочные строки не мешают импорту остальных и
+возвращаются в ответе с номерами.
+*/
+func (h *MotosHandler) handleImport(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	h.lg.Debug("MotosHandler_Import: Start!")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		writeError(w, http.StatusBadRequest, errorResponse{Error: "invalid form", Message: err.Error()})
+		return
+	}
+
+	source := r.FormValue("source")
+	if source == "" {
+		writeError(w, http.StatusBadRequest, errorResponse{
+			Error: "invalid form",
+			Fields: map[string]string{"source": "required"},
+		})
+		return
+	}
+
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errorResponse{
+			Error: "invalid form",
+			Fields: map[string]string{"file": "required"},
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errorResponse{Error: "read file error", Message: err.Error()})
+		return
+	}
+
+	result, err := h.svc.ParseAndUpdateAllMoto(r.Context(), domain.ParseOptions{
+		Source: source,
+		File: data,
+		FileName: fileHeader.Filename,
+	})
+	if err != nil {
+		if errors.Is(err, domain.SourceNotFound) {
+			writeError(w, http.StatusNotFound, errorResponse{Error: "source not found", Message: source})
+			return
+		}
+		if errors.Is(err, domain.InvalidInput) {
+			writeError(w, http.StatusBadRequest, errorResponse{Error: "invalid file", Message: err.Error()})
+			return
+		}
+		if errors.Is(err, domain.SyncAlreadyRunning) {
+			writeError(w, http.StatusConflict, errorResponse{Error: "sync already running"})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errorResponse{Error: "import error", Message: err.Error()})
+		return
+	}
+
+	response := dto.ResponseImportMotos{
+		Source: source,
+		Imported: result.Upserted,
+		Failed: result.Failed,
+		Errors: []dto.ImportRowError{},
+	}
+	for _, report := range result.Reports {
+		response.Rows += report.CardsFound
+		response.Rejected += report.CardsRejected
+		for _, rejected := range report.RejectedCards {
+			response.Errors = append(response.Errors, dto.ImportRowError{
+				Row: rejected.Index,
+				Error: rejected.Reason,
+			})
+		}
+		for column := range report.UnknownLabels {
+			response.UnknownColumns = append(response.UnknownColumns, column)
+		}
+	}
+	slices.Sort(response.UnknownColumns)
+
+	h.lg.Debug("MotosHandler_Import: End!", "source", source, "rows", response.Rows, "rejected", response.Rejected)
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *MotosHandler) handleGetClasses(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var response dto.ResponseGetMotoClasses
+	for _, info := range h.svc.GetMotoClasses(r.Context()) {
+		response.Classes = append(response.Classes, dto.MotoClass{
+			ID: string(info.Class),
+			Title: info.Title,
+			Description: info.Description,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 func (h *MotosHandler) handleParseAndUpdate(
@@ -39,12 +193,106 @@ func (h *MotosHandler) handleParseAndUpdate(
 		return
 	}
 
-	_, err := h.svc.ParseAndUpdateAllMoto(r.Context())
+	// ?source=<имя> - обойти только один источник, без параметра - все включенные
+	// ?details=true - дополнительно разобрать страницу каждого объявления
+	// ?snapshot=<id> - разобрать сохраненный снимок страниц вместо сайта, только вместе с source
+	// ?commit=true - записать разобранный снимок в базу, без него разбор пробный
+	// ?force=true - записать данные, даже если разметка сайта "уплыла" (см. domain.DetectDrift)
+	opts := domain.ParseOptions{
+		Source: r.URL.Query().Get("source"),
+		Snapshot: r.URL.Query().Get("snapshot"),
+	}
+
+	if opts.Snapshot != "" && opts.Source == "" {
+		writeError(w, http.StatusBadRequest, errorResponse{
+			Error: "invalid query",
+			Fields: map[string]string{"source": "required with snapshot"},
+		})
+		return
+	}
+
+	if details := r.URL.Query().Get("details"); details != "" {
+		withDetails, err := strconv.ParseBool(details)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errorResponse{
+				Error: "invalid query",
+				Fields: map[string]string{"details": "must be a boolean"},
+			})
+			return
+		}
+		opts.WithDetails = withDetails
+	}
+
+	if commit := r.URL.Query().Get("commit"); commit != "" {
+		committed, err := strconv.ParseBool(commit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errorResponse{
+				Error: "invalid query",
+				Fields: map[string]string{"commit": "must be a boolean"},
+			})
+			return
+		}
+		if opts.Snapshot == "" {
+			writeError(w, http.StatusBadRequest, errorResponse{
+				Error: "invalid query",
+				Fields: map[string]string{"commit": "only with snapshot"},
+			})
+			return
+		}
+		opts.Commit = committed
+	}
+
+	if force := r.URL.Query().Get("force"); force != "" {
+		forced, err := strconv.ParseBool(force)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errorResponse{
+				Error: "invalid query",
+				Fields: map[string]string{"force": "must be a boolean"},
+			})
+			return
+		}
+		opts.Force = forced
+	}
+
+	// Обход идет в фоне, за ним следят через GET /api/v1/jobs/{id}.
+	// Источник, снимок и занятость проверяются до запуска; ошибки самого обхода
+	// (например, дрейф разметки) попадают в errors задачи, подробности дрейфа -
+	// в /api/v1/motos/parseReport и /api/v1/health
+	job, err := h.jobs.StartParse(r.Context(), opts)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, errorResponse{})
+		if errors.Is(err, domain.SourceNotFound) {
+			writeError(w, http.StatusNotFound, errorResponse{Error: "source not found", Message: opts.Source})
+			return
+		}
+		if errors.Is(err, domain.SnapshotNotFound) {
+			writeError(w, http.StatusNotFound, errorResponse{Error: "snapshot not found", Message: opts.Snapshot})
+			return
+		}
+		if errors.Is(err, domain.InvalidInput) {
+			writeError(w, http.StatusBadRequest, errorResponse{Error: "invalid options", Message: err.Error()})
+			return
+		}
+		if errors.Is(err, domain.SyncAlreadyRunning) {
+			writeError(w, http.StatusConflict, errorResponse{Error: "sync already running"})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errorResponse{Error: "start job error"})
+		return
 	}
 
-	writeJSON(w, http.StatusOK, nil)
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, dto.ResponseJob{Job: job})
+}
+
+func (h *MotosHandler) handleParseReport(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	response := dto.ResponseParseReports{
+		Reports: h.svc.LastParseReports(r.Context()),
+	}
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 func (h *MotosHandler) handleGetMotos(
@@ -65,13 +313,39 @@ func (h *MotosHandler) handleGetMotos(
 		return	
 	}
 
+	// Класс принимается id ("chopper") или названием ("Чоппер"), "-" - любой
+	class, ok := domain.ParseMotoClass(request.MotoType)
+	if !ok {
+		writeError(w, http.StatusBadRequest, errorResponse{
+			Error: "invalid request",
+			Fields: map[string]string{"moto_type": "unknown moto class, see /api/v1/motos/classes"},
+		})
+		return
+	}
+
 	filter := domain.NewMotoFilter(
 		request.EngineSizeOption,
 		request.YearOption,
 		request.MileageOption,
 		request.PriceMax,
-		request.MotoType,
+		class,
 	)
+	filter.Brand = strings.TrimSpace(request.Brand)
+	filter.Model = strings.TrimSpace(request.Model)
+	filter.City = strings.TrimSpace(request.City)
+	filter.IncludeInactive = request.IncludeInactive
+	filter.PriceDropped = request.PriceDropped
+
+	switch request.Powertrain {
+	case "", domain.PowertrainICE, domain.PowertrainElectric:
+		filter.Powertrain = request.Powertrain
+	default:
+		writeError(w, http.StatusBadRequest, errorResponse{
+			Error: "invalid request",
+			Fields: map[string]string{"powertrain": "must be ice or electric"},
+		})
+		return
+	}
 
 	motos, err := h.svc.GetMotosByFilter(r.Context(), filter)
 	if err != nil {
@@ -0,0 +1,100 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/vvetta/electoral_system/internal/adapters/http/dto"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+type SalonsHandler struct {
+	svc usecase.SalonService
+	lg usecase.Logger
+}
+
+func NewSalonsHandler(
+	svc usecase.SalonService,
+	lg usecase.Logger,
+) *SalonsHandler {
+	return &SalonsHandler{
+		svc: svc,
+		lg: lg,
+	}
+}
+
+func (h *SalonsHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/salons", h.handleGetSalons)
+	mux.HandleFunc("GET /api/v1/salons/{id}/motos", h.handleGetSalonMotos)
+}
+
+func (h *SalonsHandler) handleGetSalons(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	h.lg.Debug("SalonsHandler_GetSalons: Start!")
+
+	// ?city=<город> - только салоны этого города
+	filter := domain.SalonFilter{
+		City: r.URL.Query().Get("city"),
+	}
+
+	salons, err := h.svc.GetSalons(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errorResponse{})
+		return
+	}
+
+	response := dto.ResponseGetSalons{
+		Salons: salons,
+	}
+
+	h.lg.Debug("SalonsHandler_GetSalons: End!")
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *SalonsHandler) handleGetSalonMotos(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	h.lg.Debug("SalonsHandler_GetSalonMotos: Start!")
+
+	salonID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errorResponse{
+			Error: "invalid path",
+			Fields: map[string]string{"id": "must be a positive integer"},
+		})
+		return
+	}
+
+	salon, err := h.svc.GetSalon(r.Context(), uint(salonID))
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+
+	motos, err := h.svc.GetSalonMotos(r.Context(), salon.ID)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+
+	response := dto.ResponseGetSalonMotos{
+		Salon: salon,
+		Motos: motos,
+	}
+
+	h.lg.Debug("SalonsHandler_GetSalonMotos: End!")
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *SalonsHandler) writeServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.RecordNotFound) {
+		writeError(w, http.StatusNotFound, errorResponse{Error: "salon not found"})
+		return
+	}
+	writeError(w, http.StatusInternalServerError, errorResponse{})
+}
@@ -0,0 +1,81 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/vvetta/electoral_system/internal/adapters/http/dto"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+type SchedulerHandler struct {
+	scheduler usecase.SyncScheduler
+	lg usecase.Logger
+}
+
+func NewSchedulerHandler(
+	scheduler usecase.SyncScheduler,
+	lg usecase.Logger,
+) *SchedulerHandler {
+	return &SchedulerHandler{
+		scheduler: scheduler,
+		lg: lg,
+	}
+}
+
+func (h *SchedulerHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/scheduler", h.handleStatus)
+	mux.HandleFunc("POST /api/v1/scheduler/enable", h.handleEnable)
+	mux.HandleFunc("POST /api/v1/scheduler/disable", h.handleDisable)
+	mux.HandleFunc("POST /api/v1/scheduler/run", h.handleRunNow)
+}
+
+func (h *SchedulerHandler) handleStatus(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	writeJSON(w, http.StatusOK, dto.ResponseScheduler{
+		Scheduler: h.scheduler.Status(r.Context()),
+	})
+}
+
+func (h *SchedulerHandler) handleEnable(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	writeJSON(w, http.StatusOK, dto.ResponseScheduler{
+		Scheduler: h.scheduler.SetEnabled(r.Context(), true),
+	})
+}
+
+func (h *SchedulerHandler) handleDisable(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	writeJSON(w, http.StatusOK, dto.ResponseScheduler{
+		Scheduler: h.scheduler.SetEnabled(r.Context(), false),
+	})
+}
+
+/*
+handleRunNow запускает обход вне расписания и сразу отвечает 202,
+за ходом обхода можно следить через GET /api/v1/scheduler.
+*/
+func (h *SchedulerHandler) handleRunNow(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	if err := h.scheduler.RunNow(r.Context()); err != nil {
+		if errors.Is(err, domain.SyncAlreadyRunning) {
+			writeError(w, http.StatusConflict, errorResponse{Error: "sync already running"})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errorResponse{})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, dto.ResponseScheduler{
+		Scheduler: h.scheduler.Status(r.Context()),
+	})
+}
@@ -12,13 +12,31 @@ type Server struct {
 
 func NewServer(
 	motosSVC usecase.MotoService,
+	salonsSVC usecase.SalonService,
+	jobsSVC usecase.JobService,
+	scheduler usecase.SyncScheduler,
 	lg usecase.Logger,
 ) *Server {
 	mux := http.NewServeMux()
 
-	motosHandler := NewMotosHandler(motosSVC, lg)
+	motosHandler := NewMotosHandler(motosSVC, jobsSVC, lg)
 	motosHandler.Register(mux)
 
+	salonsHandler := NewSalonsHandler(salonsSVC, lg)
+	salonsHandler.Register(mux)
+
+	healthHandler := NewHealthHandler(motosSVC, lg)
+	healthHandler.Register(mux)
+
+	schedulerHandler := NewSchedulerHandler(scheduler, lg)
+	schedulerHandler.Register(mux)
+
+	jobsHandler := NewJobsHandler(jobsSVC, lg)
+	jobsHandler.Register(mux)
+
+	syncRunsHandler := NewSyncRunsHandler(motosSVC, lg)
+	syncRunsHandler.Register(mux)
+
 	mux.Handle("/", http.FileServer(http.Dir("web/")))
 
 	return &Server{
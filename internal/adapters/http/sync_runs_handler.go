@@ -0,0 +1,86 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/vvetta/electoral_system/internal/adapters/http/dto"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+type SyncRunsHandler struct {
+	svc usecase.MotoService
+	lg usecase.Logger
+}
+
+func NewSyncRunsHandler(
+	svc usecase.MotoService,
+	lg usecase.Logger,
+) *SyncRunsHandler {
+	return &SyncRunsHandler{
+		svc: svc,
+		lg: lg,
+	}
+}
+
+func (h *SyncRunsHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/sync-runs", h.handleGetSyncRuns)
+}
+
+/*
+handleGetSyncRuns отдает журнал обходов, новые записи первыми.
+?source=<имя> - только один источник
+?status=ok|failed - только удачные или только упавшие обходы
+?kind=crawl|snapshot|file - только обходы сайта, разборы снимков или файлы
+?limit=N - сколько записей, по умолчанию domain.DefaultSyncRunsLimit
+Время последнего удачного обхода сайта: ?status=ok&kind=crawl&limit=1.
+*/
+func (h *SyncRunsHandler) handleGetSyncRuns(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	h.lg.Debug("SyncRunsHandler_GetSyncRuns: Start!")
+
+	filter := domain.SyncRunFilter{
+		Source: r.URL.Query().Get("source"),
+		Status: r.URL.Query().Get("status"),
+		Kind: r.URL.Query().Get("kind"),
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, errorResponse{
+				Error: "invalid query",
+				Fields: map[string]string{"limit": "must be a positive integer"},
+			})
+			return
+		}
+		filter.Limit = n
+	}
+
+	runs, err := h.svc.GetSyncRuns(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, domain.InvalidInput) {
+			// Статус сервис проверяет первым
+			fields := map[string]string{"kind": "must be crawl, snapshot or file"}
+			switch filter.Status {
+			case "", domain.SyncRunStatusOK, domain.SyncRunStatusFailed:
+			default:
+				fields = map[string]string{"status": "must be ok or failed"}
+			}
+			writeError(w, http.StatusBadRequest, errorResponse{
+				Error: "invalid query",
+				Fields: fields,
+			})
+			return
+		}
+		writeError(w, http.StatusInternalServerError, errorResponse{})
+		return
+	}
+
+	h.lg.Debug("SyncRunsHandler_GetSyncRuns: End!", "count", len(runs))
+	writeJSON(w, http.StatusOK, dto.ResponseSyncRuns{Runs: runs})
+}
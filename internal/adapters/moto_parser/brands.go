@@ -0,0 +1,189 @@
+package motoparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Сколько слов заголовка может занимать марка или модель ("Harley Davidson", "XT 1200Z Super Tenere")
+const maxNameWords = 5
+
+var yearWordRe = regexp.MustCompile(`^(19|20)\d\d(г\.?)?,?$`)
+
+/*
+BrandDictionary разбирает заголовок объявления на марку, модель и комплектацию.
+Словарь лежит в json (configs/brands.json): для каждой марки - каноничное
+название, варианты написания (кириллица, без дефиса) и известные модели.
+Модели нужны, чтобы правильно отделять многословные модели ("Road King")
+от комплектации; для незнакомых моделей работает эвристика, см. splitModel.
+*/
+type BrandDictionary struct {
+	Brands []BrandEntry `json:"brands"`
+
+	aliases map[string]*BrandEntry // ключ написания -> марка
+}
+
+type BrandEntry struct {
+	Name string `json:"name"`
+	Aliases []string `json:"aliases"`
+	Models []string `json:"models"`
+}
+
+func LoadBrandDictionary(path string) (*BrandDictionary, error) {
+	var d BrandDictionary
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read brands config error: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("decode brands config %s error: %w", path, err)
+	}
+
+	if err := d.init(); err != nil {
+		return nil, fmt.Errorf("invalid brands config %s: %w", path, err)
+	}
+
+	return &d, nil
+}
+
+func (d *BrandDictionary) init() error {
+	var errs []error
+	d.aliases = make(map[string]*BrandEntry)
+
+	for i := range d.Brands {
+		brand := &d.Brands[i]
+		if strings.TrimSpace(brand.Name) == "" {
+			errs = append(errs, fmt.Errorf("brand #%d: name is empty", i))
+			continue
+		}
+
+		for _, alias := range append([]string{brand.Name}, brand.Aliases...) {
+			key := nameKey(alias)
+			if key == "" {
+				errs = append(errs, fmt.Errorf("brand %q: empty alias", brand.Name))
+				continue
+			}
+			if len(strings.Fields(alias)) > maxNameWords {
+				errs = append(errs, fmt.Errorf("brand %q: alias %q is too long", brand.Name, alias))
+				continue
+			}
+			if other, ok := d.aliases[key]; ok && other != brand {
+				errs = append(errs, fmt.Errorf("alias %q used for both %q and %q", alias, other.Name, brand.Name))
+				continue
+			}
+			d.aliases[key] = brand
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+/*
+Split делит заголовок на марку, модель и комплектацию:
+"Ямаха MT-09 Tracer 2019" -> "Yamaha", "MT-09", "Tracer".
+Марка ищется по словарю в любом месте заголовка ("Мотоцикл BMW R 1250 GS"),
+слова перед ней отбрасываются. Если марка не найдена, все три части пустые.
+Словарь может быть nil - тогда заголовки не разбираются.
+*/
+func (d *BrandDictionary) Split(title string) (brand, model, trim string) {
+	if d == nil {
+		return "", "", ""
+	}
+
+	words := strings.Fields(title)
+	for i := range words {
+		// Сначала самое длинное написание: "Harley Davidson" раньше "Harley"
+		for j := min(i+maxNameWords, len(words)); j > i; j-- {
+			entry, ok := d.aliases[nameKey(strings.Join(words[i:j], " "))]
+			if !ok {
+				continue
+			}
+
+			model, trim := entry.splitModel(words[j:])
+			return entry.Name, model, trim
+		}
+	}
+
+	return "", "", ""
+}
+
+/*
+splitModel отделяет модель от комплектации. Сначала ищется самая длинная
+модель из словаря марки, иначе моделью считается первое слово, а короткий
+буквенный префикс склеивается со следующим номером: "R 1250 ..." -> "R 1250".
+Год выпуска из комплектации выкидывается.
+*/
+func (b *BrandEntry) splitModel(words []string) (string, string) {
+	var rest []string
+	for _, w := range words {
+		if !yearWordRe.MatchString(strings.ToLower(w)) {
+			rest = append(rest, w)
+		}
+	}
+	if len(rest) == 0 {
+		return "", ""
+	}
+
+	for j := min(maxNameWords, len(rest)); j > 0; j-- {
+		key := nameKey(strings.Join(rest[:j], " "))
+		for _, model := range b.Models {
+			if nameKey(model) == key {
+				return model, strings.Join(rest[j:], " ")
+			}
+		}
+	}
+
+	n := 1
+	if len(rest) > 1 && len([]rune(rest[0])) <= 2 && isLetters(rest[0]) && hasDigit(rest[1]) {
+		n = 2
+	}
+
+	return strings.Join(rest[:n], " "), strings.Join(rest[n:], " ")
+}
+
+// Кириллические буквы, которые в заголовках пишут вместо похожих латинских: "МТ-09", "КТМ"
+var homoglyphs = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x',
+}
+
+// nameKey - ключ для сравнения написаний: без регистра, пробелов, дефисов и
+// кириллицы-двойников, "Harley-Davidson" == "harley davidson", "R 1250 GS" == "R1250GS".
+func nameKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if latin, ok := homoglyphs[r]; ok {
+			r = latin
+		}
+		if unicode.IsSpace(r) || r == '-' || r == '_' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func hasDigit(s string) bool {
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package motoparser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBrandDictionary_Split(t *testing.T) {
+	brands, err := LoadBrandDictionary(brandsPath)
+	if err != nil {
+		t.Fatalf("load brands error: %v", err)
+	}
+
+	tests := []struct {
+		title string
+		brand, model, trim string
+	}{
+		{title: "Yamaha MT-09", brand: "Yamaha", model: "MT-09"},
+		{title: "Ямаха МТ-09 Tracer 2019", brand: "Yamaha", model: "MT-09", trim: "Tracer"},
+		{title: "Harley-Davidson Road King Special", brand: "Harley-Davidson", model: "Road King", trim: "Special"},
+		{title: "Харлей Дэвидсон Iron 883", brand: "Harley-Davidson", model: "Iron 883"},
+		{title: "Мотоцикл BMW R1250GS Adventure", brand: "BMW", model: "R 1250 GS", trim: "Adventure"},
+		{title: "BMW R 1250 GS", brand: "BMW", model: "R 1250 GS"},
+		{title: "Honda CB 1300 Super Four", brand: "Honda", model: "CB 1300", trim: "Super Four"},
+		{title: "KTM 300 EXC TPI", brand: "KTM", model: "300", trim: "EXC TPI"},
+		{title: "Super Soco TC Max", brand: "Super Soco", model: "TC Max"},
+		{title: "Неизвестная марка X1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			brand, model, trim := brands.Split(tt.title)
+			if brand != tt.brand || model != tt.model || trim != tt.trim {
+				t.Errorf("got %q/%q/%q, want %q/%q/%q", brand, model, trim, tt.brand, tt.model, tt.trim)
+			}
+		})
+	}
+}
+
+func TestLoadBrandDictionary_DuplicateAlias(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brands.json")
+	config := `{"brands": [{"name": "Honda", "aliases": ["Хонда"]}, {"name": "Hondo", "aliases": ["хонда"]}]}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config error: %v", err)
+	}
+
+	if _, err := LoadBrandDictionary(path); err == nil || !strings.Contains(err.Error(), "used for both") {
+		t.Errorf("expected duplicate alias error, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package motoparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+/*
+ClassMapping переводит класс мотоцикла, как его пишет сайт, в каноничный
+domain.MotoClass. Таблица синонимов лежит в json (configs/moto_classes.json):
+id класса -> варианты написания. Написания сравниваются через nameKey,
+поэтому "Спорт-турист" и "спорт турист" - одно и то же.
+*/
+type ClassMapping struct {
+	synonyms map[string]domain.MotoClass // ключ написания -> класс
+}
+
+func LoadClassMapping(path string) (*ClassMapping, error) {
+	var table map[domain.MotoClass][]string
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read moto classes config error: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("decode moto classes config %s error: %w", path, err)
+	}
+
+	m, err := newClassMapping(table)
+	if err != nil {
+		return nil, fmt.Errorf("invalid moto classes config %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+func newClassMapping(table map[domain.MotoClass][]string) (*ClassMapping, error) {
+	var errs []error
+	m := &ClassMapping{synonyms: make(map[string]domain.MotoClass)}
+
+	// Название класса из domain - тоже синоним, его в конфиге можно не повторять
+	for _, info := range domain.MotoClasses() {
+		m.synonyms[nameKey(info.Title)] = info.Class
+		m.synonyms[nameKey(string(info.Class))] = info.Class
+	}
+
+	for configured, synonyms := range table {
+		// В конфиге может стоять и название класса, храним каноничный id
+		class, ok := domain.ParseMotoClass(string(configured))
+		if !ok || class == "" {
+			errs = append(errs, fmt.Errorf("unknown moto class %q", configured))
+			continue
+		}
+
+		for _, synonym := range synonyms {
+			key := nameKey(synonym)
+			if key == "" {
+				errs = append(errs, fmt.Errorf("class %q: empty synonym", class))
+				continue
+			}
+			if other, ok := m.synonyms[key]; ok && other != class {
+				errs = append(errs, fmt.Errorf("synonym %q used for both %q and %q", synonym, other, class))
+				continue
+			}
+			m.synonyms[key] = class
+		}
+	}
+
+	return m, errors.Join(errs...)
+}
+
+/*
+Classify возвращает каноничный класс или "", если написание не знакомо.
+Маппинг может быть nil - тогда сопоставляются только названия классов из domain.
+*/
+func (m *ClassMapping) Classify(motoType string) domain.MotoClass {
+	if m == nil {
+		class, ok := domain.ParseMotoClass(motoType)
+		if !ok {
+			return ""
+		}
+		return class
+	}
+
+	return m.synonyms[nameKey(strings.TrimSpace(motoType))]
+}
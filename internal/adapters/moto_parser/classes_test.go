@@ -0,0 +1,105 @@
+package motoparser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+func TestClassMapping_Classify(t *testing.T) {
+	classes, err := LoadClassMapping(classesPath)
+	if err != nil {
+		t.Fatalf("load moto classes error: %v", err)
+	}
+
+	tests := []struct {
+		motoType string
+		want domain.MotoClass
+	}{
+		{"Спорт-турист", domain.MotoClassSportTouring},
+		{"спорт турист", domain.MotoClassSportTouring},
+		{"Круизер", domain.MotoClassChopper},
+		{"Чоппер", domain.MotoClassChopper},
+		{" Нейкед ", domain.MotoClassNaked},
+		{"Туристический эндуро", domain.MotoClassAdventure},
+		{"chopper", domain.MotoClassChopper},
+		{"Электромотоцикл", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.motoType, func(t *testing.T) {
+			if got := classes.Classify(tt.motoType); got != tt.want {
+				t.Errorf("Classify(%q) = %q, want %q", tt.motoType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadClassMapping_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		err string
+	}{
+		"unknown class": {`{"hyperbike": ["Гипербайк"]}`, "unknown moto class"},
+		"duplicate synonym": {`{"sport": ["Кастом"], "chopper": ["кастом"]}`, "used for both"},
+		"empty class": {`{"-": ["Кастом"]}`, "unknown moto class"},
+		"duplicate synonym by class title": {`{"sport": ["Кастом"], "Чоппер": ["кастом"]}`, "used for both"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "moto_classes.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatalf("write config error: %v", err)
+			}
+
+			if _, err := LoadClassMapping(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected %q error, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadClassMapping_CanonicalClass(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moto_classes.json")
+	config := `{"Чоппер": ["Кастом"], " naked ": ["Стритфайтер"]}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatalf("write config error: %v", err)
+	}
+
+	classes, err := LoadClassMapping(path)
+	if err != nil {
+		t.Fatalf("load moto classes error: %v", err)
+	}
+
+	// Название и ключ с пробелами в конфиге сводятся к id класса
+	for motoType, want := range map[string]domain.MotoClass{
+		"Кастом": domain.MotoClassChopper,
+		"Стритфайтер": domain.MotoClassNaked,
+	} {
+		if got := classes.Classify(motoType); got != want {
+			t.Errorf("Classify(%q) = %q, want %q", motoType, got, want)
+		}
+	}
+}
+
+func TestMotoParser_GetAllMoto_UnknownMotoTypes(t *testing.T) {
+	srv := newCatalogServer(t, "units")
+	defer srv.Close()
+
+	parser := newTestParser(t, srv.URL+"/catalog/mototsikly/", 1, WithRateInterval(0))
+
+	_, report, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("get all moto error: %v", err)
+	}
+
+	if report.UnknownMotoTypes["Электромотоцикл"] != 1 || len(report.UnknownMotoTypes) != 1 {
+		t.Errorf("unexpected unknown moto types: %v", report.UnknownMotoTypes)
+	}
+}
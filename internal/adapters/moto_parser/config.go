@@ -0,0 +1,189 @@
+package motoparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+// Типы парсеров: каталог mr-moto.ru и выгрузка дилера файлом
+const (
+	KindMrMoto = "mr-moto"
+	KindFile = "file"
+)
+
+type SourcesConfig struct {
+	Brands string `json:"brands"` // путь к словарю марок, см. BrandDictionary. Пусто - заголовки не разбираются
+	Classes string `json:"classes"` // путь к таблице синонимов классов, см. ClassMapping
+	Sources []SourceConfig `json:"sources"`
+}
+
+// SourceConfig - настройки одного источника из конфигурационного файла.
+type SourceConfig struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Enabled bool `json:"enabled"`
+	URL string `json:"url"`
+	Markup string `json:"markup"` // путь к конфигу разметки, см. Markup
+	MaxPageCount int `json:"max_page_count"`
+
+	// Настройки обхода. Нулевые значения - берутся значения по умолчанию.
+	Timeout Duration `json:"timeout"`
+	Workers int `json:"workers"`
+	RateInterval *Duration `json:"rate_interval"`
+	MaxRetries *int `json:"max_retries"`
+	RetryBaseDelay Duration `json:"retry_base_delay"`
+
+	// Каталог архива сырых страниц. Пусто - страницы не сохраняются.
+	SnapshotDir string `json:"snapshot_dir"`
+
+	// Только для kind "file", см. fileParser. Path можно не задавать,
+	// если файлы приходят только через загрузку.
+	Path string `json:"path"`
+	Format string `json:"format"` // csv, json или ndjson; пусто - по расширению файла
+	Delimiter string `json:"delimiter"` // разделитель csv; пусто - запятая или точка с запятой по заголовку
+	Columns map[string]string `json:"columns"` // поле мотоцикла -> колонка файла
+}
+
+// Duration читается из json строкой вида "15s" или "500ms".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
+
+// options переводит настройки обхода из конфига в опции парсера.
+func (cfg SourceConfig) options() []Option {
+	var opts []Option
+
+	if cfg.Timeout > 0 {
+		opts = append(opts, WithTimeout(time.Duration(cfg.Timeout)))
+	}
+	if cfg.Workers > 0 {
+		opts = append(opts, WithWorkers(cfg.Workers))
+	}
+	if cfg.RateInterval != nil {
+		opts = append(opts, WithRateInterval(time.Duration(*cfg.RateInterval)))
+	}
+	if cfg.MaxRetries != nil || cfg.RetryBaseDelay > 0 {
+		maxRetries := defaultMaxRetries
+		if cfg.MaxRetries != nil {
+			maxRetries = *cfg.MaxRetries
+		}
+		baseDelay := defaultRetryBaseDelay
+		if cfg.RetryBaseDelay > 0 {
+			baseDelay = time.Duration(cfg.RetryBaseDelay)
+		}
+		opts = append(opts, WithRetries(maxRetries, baseDelay))
+	}
+	if cfg.SnapshotDir != "" {
+		opts = append(opts, WithSnapshotDir(cfg.SnapshotDir))
+	}
+
+	return opts
+}
+
+type parserFactory func(cfg SourceConfig, opts ...Option) (usecase.MotoParser, error)
+
+// Новый тип сайта добавляется сюда вместе со своей реализацией парсера.
+var parserFactories = map[string]parserFactory{
+	KindMrMoto: newMrMotoParser,
+	KindFile: newFileParser,
+}
+
+func LoadSourcesConfig(path string) (SourcesConfig, error) {
+	var cfg SourcesConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("read sources config error: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("decode sources config error: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// NewParser собирает парсер нужного типа по настройкам источника.
+// opts применяются поверх настроек из cfg.
+func NewParser(cfg SourceConfig, opts ...Option) (usecase.MotoParser, error) {
+	factory, ok := parserFactories[cfg.Kind]
+	if !ok {
+		return nil, fmt.Errorf("source %q: unknown parser kind %q", cfg.Name, cfg.Kind)
+	}
+
+	return factory(cfg, opts...)
+}
+
+// RegisterSources регистрирует в реестре все источники из конфига.
+func RegisterSources(registry *usecase.SourceRegistry, cfg SourcesConfig) error {
+	// Словарь марок и таблица классов общие для всех источников
+	var opts []Option
+	if cfg.Brands != "" {
+		brands, err := LoadBrandDictionary(cfg.Brands)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, WithBrands(brands))
+	}
+	if cfg.Classes != "" {
+		classes, err := LoadClassMapping(cfg.Classes)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, WithClasses(classes))
+	}
+
+	for _, sourceCfg := range cfg.Sources {
+		parser, err := NewParser(sourceCfg, opts...)
+		if err != nil {
+			return err
+		}
+
+		err = registry.Register(usecase.MotoSource{
+			Name: sourceCfg.Name,
+			Enabled: sourceCfg.Enabled,
+			Parser: parser,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newMrMotoParser(cfg SourceConfig, opts ...Option) (usecase.MotoParser, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("source %q: url is empty", cfg.Name)
+	}
+	if cfg.Markup == "" {
+		return nil, fmt.Errorf("source %q: markup is empty", cfg.Name)
+	}
+	if cfg.MaxPageCount <= 0 {
+		return nil, fmt.Errorf("source %q: max_page_count must be > 0", cfg.Name)
+	}
+
+	parser, err := NewMotoParser(cfg.URL, cfg.Markup, cfg.MaxPageCount, append(cfg.options(), opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("source %q: %w", cfg.Name, err)
+	}
+
+	return parser, nil
+}
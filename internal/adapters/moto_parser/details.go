@@ -0,0 +1,144 @@
+package motoparser
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+
+	"golang.org/x/net/html"
+	"golang.org/x/sync/errgroup"
+)
+
+/*
+enrichWithDetails заходит на страницу каждого объявления и дописывает
+характеристики, которых нет в карточке каталога.
+Ошибка одной страницы не ломает обход: мотоцикл остается с данными из каталога.
+*/
+func (p *motoParser) enrichWithDetails(
+	ctx context.Context,
+	src pageFetcher,
+	motos []domain.Moto,
+	rep *reportBuilder,
+) error {
+	log.Printf("MotoParser-enrichWithDetails: Start! motos: %d", len(motos))
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(p.workers)
+
+	for i := range motos {
+		if motos[i].SourceURL == "" {
+			continue
+		}
+
+		g.Go(func() error {
+			body, err := src.fetch(gctx, motos[i].SourceURL)
+			if err != nil {
+				if gctx.Err() != nil {
+					return gctx.Err()
+				}
+				log.Printf("MotoParser-enrichWithDetails: fetch %s error: %v", motos[i].SourceURL, err)
+				rep.detailPage(err)
+				return nil
+			}
+
+			pageNode, err := html.Parse(bytes.NewReader(body))
+			if err != nil {
+				log.Printf("MotoParser-enrichWithDetails: html.parse %s error: %v", motos[i].SourceURL, err)
+				rep.detailPage(err)
+				return nil
+			}
+
+			rep.detailPage(nil)
+			parseMotoDetails(pageNode, p.markup.Detail, &motos[i], rep)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	log.Print("MotoParser-enrichWithDetails: End!")
+	return nil
+}
+
+func parseMotoDetails(
+	page *html.Node,
+	markup DetailMarkup,
+	m *domain.Moto,
+	rep *reportBuilder,
+) {
+	for _, prop := range markup.prop.MatchAll(page) {
+		nameNodes := markup.propName.MatchAll(prop)
+		valueNodes := markup.propValue.MatchAll(prop)
+		if len(nameNodes) == 0 || len(valueNodes) == 0 {
+			continue
+		}
+
+		label := getText(nameNodes[0])
+		rep.labelSeen()
+		field, ok := markup.fieldByLabel(label)
+		if !ok {
+			rep.unknownLabel(label)
+			continue
+		}
+		value := getText(valueNodes[0])
+
+		switch field {
+		case fieldHorsepower:
+			if v, ok := parseLeadingNumber(value); ok {
+				m.Horsepower = int(math.Round(v))
+			}
+		case fieldWeight:
+			if v, ok := parseLeadingNumber(value); ok {
+				m.Weight = int(math.Round(v))
+			}
+		case fieldColor:
+			m.Color = value
+		case fieldTransmission:
+			m.Transmission = value
+		case fieldABS:
+			if v, ok := parseYesNo(value); ok {
+				m.ABS = &v
+			}
+		}
+	}
+
+	if descNodes := markup.description.MatchAll(page); len(descNodes) > 0 {
+		m.Description = strings.Join(strings.Fields(getText(descNodes[0])), " ")
+	}
+
+	now := time.Now()
+	m.DetailsParsedAt = &now
+}
+
+/*
+parseLeadingNumber достает первое число из строки вида "73,4 л.с." или "1 200 кг".
+В отличие от parseIntFromString не склеивает все цифры строки подряд.
+Разделители разрядов и дробной части - как у цен, см. scanNumber.
+*/
+func parseLeadingNumber(s string) (float64, bool) {
+	runes := []rune(s)
+	for i, r := range runes {
+		if isASCIIDigit(r) {
+			whole, frac, _ := scanNumber(runes, i)
+			return float64(whole) + frac, true
+		}
+	}
+	return 0, false
+}
+
+func parseYesNo(s string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "да", "есть", "yes", "+":
+		return true, true
+	case "нет", "no", "-":
+		return false, true
+	}
+	return false, false
+}
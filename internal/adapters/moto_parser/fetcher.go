@@ -0,0 +1,193 @@
+package motoparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+const (
+	defaultTimeout = 15 * time.Second
+	defaultWorkers = 4
+	defaultRateInterval = 200 * time.Millisecond
+	defaultMaxRetries = 3
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	maxRetryDelay = 10 * time.Second
+)
+
+/*
+fetcher скачивает страницы источника.
+Запросы к одному хосту идут не чаще одного раза в rateInterval,
+временные ошибки сети и ответы 5xx/429 повторяются с экспоненциальной задержкой.
+*/
+type fetcher struct {
+	client *http.Client
+	rateInterval time.Duration
+	maxRetries int
+	retryBaseDelay time.Duration
+
+	mu sync.Mutex
+	limiters map[string]*hostLimiter
+}
+
+func newFetcher() *fetcher {
+	return &fetcher{
+		client: &http.Client{Timeout: defaultTimeout},
+		rateInterval: defaultRateInterval,
+		maxRetries: defaultMaxRetries,
+		retryBaseDelay: defaultRetryBaseDelay,
+		limiters: make(map[string]*hostLimiter),
+	}
+}
+
+// statusError - ответ сервера с неуспешным статусом.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.code)
+}
+
+// isNotFound - страницы нет на сайте (404 или 410).
+func isNotFound(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && (se.code == http.StatusNotFound || se.code == http.StatusGone)
+}
+
+func (f *fetcher) fetch(ctx context.Context, pageURL string) ([]byte, error) {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	limiter := f.limiter(parsedURL.Host)
+
+	var lastErr error
+	for attempt := 0; attempt <= f.maxRetries; attempt++ {
+		if attempt > 0 {
+			delay := f.retryDelay(attempt)
+			log.Printf("MotoParser-fetch: retry %d for %s in %s: %v", attempt, pageURL, delay, lastErr)
+			if err := sleepCtx(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		body, err := f.do(ctx, pageURL)
+		if err == nil {
+			return body, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		lastErr = err
+		if !isRetryable(err) {
+			break
+		}
+	}
+
+	return nil, fmt.Errorf("%w: fetch %s: %w", domain.ParseMotoError, pageURL, lastErr)
+}
+
+func (f *fetcher) do(ctx context.Context, pageURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, &statusError{code: resp.StatusCode}
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func (f *fetcher) retryDelay(attempt int) time.Duration {
+	delay := f.retryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+func (f *fetcher) limiter(host string) *hostLimiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	l, ok := f.limiters[host]
+	if !ok {
+		l = &hostLimiter{interval: f.rateInterval}
+		f.limiters[host] = l
+	}
+
+	return l
+}
+
+// Ошибки 4xx (кроме 429) повторять бессмысленно, остальные считаем временными.
+func isRetryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= 500 || statusErr.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// hostLimiter выдает слоты на запросы к хосту не чаще, чем раз в interval.
+type hostLimiter struct {
+	mu sync.Mutex
+	interval time.Duration
+	next time.Time
+}
+
+func (l *hostLimiter) wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleepCtx(ctx, time.Until(slot))
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,605 @@
+package motoparser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+// Форматы файлов выгрузки
+const (
+	FormatCSV = "csv"
+	FormatJSON = "json"
+	FormatNDJSON = "ndjson"
+)
+
+// Поля мотоцикла, которые можно взять из файла. Ключи - имена полей в columns конфига.
+const (
+	columnExternalID = "external_id"
+	columnName = "name"
+	columnBrand = "brand"
+	columnModel = "model"
+	columnTrim = "trim"
+	columnYear = "year"
+	columnMileage = "mileage"
+	columnEngineSize = "engine_size"
+	columnMotoType = "moto_type"
+	columnLocation = "location"
+	columnPrice = "price"
+	columnOldPrice = "old_price"
+	columnCurrency = "currency"
+	columnSourceURL = "source_url"
+	columnImageURL = "image_url"
+	columnDealerURL = "dealer_url"
+	columnColor = "color"
+	columnDescription = "description"
+)
+
+var fileColumns = []string{
+	columnExternalID, columnName, columnBrand, columnModel, columnTrim, columnYear,
+	columnMileage, columnEngineSize, columnMotoType, columnLocation, columnPrice,
+	columnOldPrice, columnCurrency, columnSourceURL, columnImageURL, columnDealerURL,
+	columnColor, columnDescription,
+}
+
+// Самый старый год выпуска, который считаем правдой, а не опечаткой
+const minYear = 1900
+
+// Строка файла: номер (строка csv/ndjson или элемент json-массива) и значения по колонкам
+type fileRow struct {
+	num int
+	values map[string]string
+}
+
+/*
+fileParser читает выгрузку дилера из CSV, JSON (массив объектов) или NDJSON.
+Колонки файла сопоставляются с полями мотоцикла через columns из конфига:
+{"name": "Наименование", "price": "Цена, руб"}; не указанные поля ищутся
+в файле под своим именем ("year", "mileage", ...).
+Значения разбираются так же, как на сайте: "12 500 км", "1.2 л", "от 990 000 ₽".
+Строка с ошибкой не прерывает импорт, а попадает в отчет как отбракованная
+карточка с номером строки.
+*/
+type fileParser struct {
+	source string
+	path string
+	format string
+	delimiter rune
+	columns map[string]string // поле -> колонка файла
+	dictionaries
+}
+
+func newFileParser(cfg SourceConfig, opts ...Option) (usecase.MotoParser, error) {
+	columns := make(map[string]string, len(fileColumns))
+	for _, field := range fileColumns {
+		columns[field] = field
+	}
+
+	var errs []error
+	for field, column := range cfg.Columns {
+		if _, ok := columns[field]; !ok {
+			errs = append(errs, fmt.Errorf("unknown field %q in columns", field))
+			continue
+		}
+		if strings.TrimSpace(column) == "" {
+			errs = append(errs, fmt.Errorf("field %q: column is empty", field))
+			continue
+		}
+		columns[field] = column
+	}
+
+	switch cfg.Format {
+	case "", FormatCSV, FormatJSON, FormatNDJSON:
+	default:
+		errs = append(errs, fmt.Errorf("unknown format %q", cfg.Format))
+	}
+
+	var delimiter rune
+	if cfg.Delimiter != "" {
+		runes := []rune(cfg.Delimiter)
+		if len(runes) != 1 {
+			errs = append(errs, fmt.Errorf("delimiter must be a single character"))
+		} else {
+			delimiter = runes[0]
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("source %q: %w", cfg.Name, err)
+	}
+
+	return &fileParser{
+		source: cfg.Name,
+		path: cfg.Path,
+		format: cfg.Format,
+		delimiter: delimiter,
+		columns: columns,
+		// Из общих опций файлу нужны только словари марок и классов
+		dictionaries: newParserOptions(opts).dictionaries,
+	}, nil
+}
+
+func (p *fileParser) GetAllMoto(
+	ctx context.Context,
+	opts domain.ParseOptions,
+) ([]domain.Moto, domain.ParseReport, error) {
+	return collectMotos(ctx, opts, p.StreamAllMoto)
+}
+
+// StreamAllMoto разбирает загруженный файл (opts.File) или файл из конфига.
+func (p *fileParser) StreamAllMoto(
+	ctx context.Context,
+	opts domain.ParseOptions,
+	out chan<- domain.Moto,
+) (domain.ParseReport, error) {
+	log.Print("FileParser: Start!")
+
+	rep := newReportBuilder()
+
+	if opts.Snapshot != "" {
+		err := fmt.Errorf("%w: file source has no snapshots", domain.SnapshotNotFound)
+		return rep.finish(err), err
+	}
+
+	data, name := opts.File, opts.FileName
+	if data == nil {
+		if p.path == "" {
+			err := fmt.Errorf("%w: file is not uploaded and path is not configured", domain.InvalidInput)
+			return rep.finish(err), err
+		}
+
+		var err error
+		data, err = os.ReadFile(p.path)
+		if err != nil {
+			err = fmt.Errorf("%w: read file: %v", domain.InternalError, err)
+			return rep.finish(err), err
+		}
+		name = p.path
+	}
+
+	format, err := p.detectFormat(name)
+	if err != nil {
+		return rep.finish(err), err
+	}
+	rep.pageFetched()
+
+	seen := make(map[string]int) // ключ объявления -> строка, где он уже встречался
+	emit := func(row fileRow) error {
+		rep.cardsFound(1)
+
+		moto, err := p.parseRow(row)
+		if err == nil {
+			err = checkDuplicate(moto, row.num, seen)
+		}
+		if err != nil {
+			rep.cardRejected(name, row.num, err)
+			return nil
+		}
+
+		if moto.Class == "" && moto.MotoType != "" {
+			rep.unknownMotoType(moto.MotoType)
+		}
+		rep.motoParsed(moto)
+		select {
+		case out <- moto:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	header := func(columns []string) error {
+		return p.checkHeader(columns, rep)
+	}
+
+	if err := readRows(data, format, p.delimiter, header, emit, rep, name); err != nil {
+		log.Printf("FileParser: read %s error: %v", name, err)
+		return rep.finish(err), err
+	}
+
+	log.Print("FileParser: End!")
+	return rep.finish(nil), nil
+}
+
+// detectFormat берет формат из конфига, а если он не задан - по расширению файла.
+func (p *fileParser) detectFormat(name string) (string, error) {
+	if p.format != "" {
+		return p.format, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".txt":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	}
+
+	return "", fmt.Errorf("%w: unknown file format %q, expected csv, json or ndjson", domain.InvalidInput, name)
+}
+
+/*
+checkHeader проверяет колонки файла: без названия (или хотя бы марки)
+мотоцикл не собрать, поэтому такой файл отклоняется целиком.
+Колонки, которые не сопоставлены ни с одним полем, попадают в UnknownLabels.
+*/
+func (p *fileParser) checkHeader(columns []string, rep *reportBuilder) error {
+	known := make(map[string]bool, len(p.columns))
+	for _, column := range p.columns {
+		known[columnKey(column)] = true
+	}
+
+	present := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		rep.labelSeen()
+		present[columnKey(column)] = true
+		if !known[columnKey(column)] {
+			rep.unknownLabel(column)
+		}
+	}
+
+	if !present[columnKey(p.columns[columnName])] && !present[columnKey(p.columns[columnBrand])] {
+		return fmt.Errorf("%w: file has neither %q nor %q column", domain.InvalidInput, p.columns[columnName], p.columns[columnBrand])
+	}
+
+	return nil
+}
+
+// parseRow собирает мотоцикл из строки файла.
+func (p *fileParser) parseRow(row fileRow) (domain.Moto, error) {
+	get := func(field string) string {
+		return strings.TrimSpace(row.values[columnKey(p.columns[field])])
+	}
+
+	var errs []error
+	fail := func(field string, err error) {
+		errs = append(errs, fmt.Errorf("column %q: %w", p.columns[field], err))
+	}
+
+	moto := domain.Moto{
+		Name: get(columnName),
+		Brand: get(columnBrand),
+		Model: get(columnModel),
+		Trim: get(columnTrim),
+		MotoType: get(columnMotoType),
+		Location: get(columnLocation),
+		Color: get(columnColor),
+		Description: get(columnDescription),
+	}
+
+	if moto.Name == "" {
+		moto.Name = strings.Join(strings.Fields(moto.Brand+" "+moto.Model+" "+moto.Trim), " ")
+	}
+	if moto.Name == "" {
+		fail(columnName, errors.New("name is empty"))
+	}
+	if moto.Brand == "" {
+		moto.Brand, moto.Model, moto.Trim = p.brands.Split(moto.Name)
+	}
+
+	if externalID := get(columnExternalID); externalID != "" {
+		// Номера у разных дилеров могут совпадать
+		moto.ExternalID = p.source + ":" + externalID
+	}
+
+	if value := get(columnYear); value != "" {
+		year, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(strings.TrimSuffix(value, "г.")), "г"))
+		switch {
+		case err != nil:
+			fail(columnYear, fmt.Errorf("invalid year %q", value))
+		case year < minYear || year > time.Now().Year()+1:
+			fail(columnYear, fmt.Errorf("year %d is out of range", year))
+		default:
+			moto.Year = year
+		}
+	}
+
+	if value := get(columnMileage); value != "" {
+		m, ok := parseMileage(value)
+		if !ok {
+			fail(columnMileage, fmt.Errorf("invalid mileage %q", value))
+		}
+		moto.Mileage, moto.MileageOriginal, moto.MileageUnit = m.km, m.original, m.unit
+	}
+
+	if value := get(columnEngineSize); value != "" {
+		e, ok := parseEngine(value)
+		if !ok {
+			fail(columnEngineSize, fmt.Errorf("invalid engine size %q", value))
+		}
+		moto.EngineSize, moto.EngineSizeUnit, moto.Powertrain, moto.MotorPowerKW = e.cc, e.unit, e.powertrain, e.powerKW
+	}
+	if moto.Powertrain == "" && isElectric(moto) {
+		moto.Powertrain = domain.PowertrainElectric
+	}
+
+	moto.Class = p.classes.Classify(moto.MotoType)
+
+	pr := price{status: domain.PriceStatusUnknown}
+	if value := get(columnPrice); value != "" {
+		pr = parsePriceText(value, get(columnOldPrice))
+		if pr.status == domain.PriceStatusUnknown {
+			fail(columnPrice, fmt.Errorf("invalid price %q", value))
+		}
+	}
+	if currency := strings.ToUpper(get(columnCurrency)); currency != "" {
+		if len(currency) != 3 {
+			fail(columnCurrency, fmt.Errorf("invalid currency %q, expected ISO code", currency))
+		}
+		pr.currency = currency
+	}
+	moto.Price, moto.OldPrice, moto.PriceCurrency, moto.PriceStatus = pr.current, pr.old, pr.currency, pr.status
+
+	for field, dst := range map[string]*string{
+		columnSourceURL: &moto.SourceURL,
+		columnImageURL: &moto.ImageURL,
+		columnDealerURL: &moto.DealerURL,
+	} {
+		value := get(field)
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+			fail(field, fmt.Errorf("invalid url %q", value))
+			continue
+		}
+		*dst = value
+	}
+
+	if len(errs) > 0 {
+		return domain.Moto{}, errors.Join(errs...)
+	}
+
+	return moto, nil
+}
+
+// checkDuplicate не дает одному объявлению попасть в файл дважды:
+// в одной пачке записи в базу повтор ключа ломает всю транзакцию.
+func checkDuplicate(moto domain.Moto, num int, seen map[string]int) error {
+	key := moto.ExternalID
+	if key == "" {
+		key = moto.ListingKey()
+	}
+
+	if first, ok := seen[key]; ok {
+		return fmt.Errorf("duplicate of row %d", first)
+	}
+	seen[key] = num
+
+	return nil
+}
+
+/*
+readRows читает строки файла и отдает их в emit.
+header вызывается один раз со списком колонок (для JSON - ключи первого разобранного объекта).
+Ошибка разбора одной строки пишется в отчет, ошибка формата всего файла
+(не массив в JSON, пустой CSV) прерывает чтение.
+*/
+func readRows(
+	data []byte,
+	format string,
+	delimiter rune,
+	header func(columns []string) error,
+	emit func(row fileRow) error,
+	rep *reportBuilder,
+	name string,
+) error {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // BOM из Excel
+
+	switch format {
+	case FormatCSV:
+		return readCSV(data, delimiter, header, emit, rep, name)
+	case FormatJSON:
+		return readJSON(data, header, emit, rep, name)
+	case FormatNDJSON:
+		return readNDJSON(data, header, emit, rep, name)
+	}
+
+	return fmt.Errorf("%w: unknown format %q", domain.InvalidInput, format)
+}
+
+func readCSV(
+	data []byte,
+	delimiter rune,
+	header func(columns []string) error,
+	emit func(row fileRow) error,
+	rep *reportBuilder,
+	name string,
+) error {
+	if delimiter == 0 {
+		delimiter = detectDelimiter(data)
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	columns, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: csv file is empty", domain.InvalidInput)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: read csv header: %v", domain.InvalidInput, err)
+	}
+	if err := header(columns); err != nil {
+		return err
+	}
+
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rep.cardsFound(1)
+			rep.cardRejected(name, parseErr.Line, parseErr.Err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%w: read csv: %v", domain.InvalidInput, err)
+		}
+
+		line, _ := r.FieldPos(0)
+		row := fileRow{num: line, values: make(map[string]string, len(columns))}
+		for i, column := range columns {
+			if i < len(record) {
+				row.values[columnKey(column)] = record[i]
+			}
+		}
+
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+}
+
+// detectDelimiter выбирает между запятой и точкой с запятой (так сохраняет русский Excel) по заголовку.
+func detectDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+func readJSON(
+	data []byte,
+	header func(columns []string) error,
+	emit func(row fileRow) error,
+	rep *reportBuilder,
+	name string,
+) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("%w: json file must be an array of objects: %v", domain.InvalidInput, err)
+	}
+
+	headerChecked := false
+	for i, item := range items {
+		row, err := decodeJSONRow(item, i+1)
+		if err == nil && !headerChecked {
+			if err := header(sortedKeys(row.values)); err != nil {
+				return err
+			}
+			headerChecked = true
+		}
+		if err != nil {
+			rep.cardsFound(1)
+			rep.cardRejected(name, i+1, err)
+			continue
+		}
+
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readNDJSON(
+	data []byte,
+	header func(columns []string) error,
+	emit func(row fileRow) error,
+	rep *reportBuilder,
+	name string,
+) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	headerChecked := false
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		row, err := decodeJSONRow(scanner.Bytes(), line)
+		if err == nil && !headerChecked {
+			if err := header(sortedKeys(row.values)); err != nil {
+				return err
+			}
+			headerChecked = true
+		}
+		if err != nil {
+			rep.cardsFound(1)
+			rep.cardRejected(name, line, err)
+			continue
+		}
+
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: read ndjson: %v", domain.InvalidInput, err)
+	}
+
+	return nil
+}
+
+// decodeJSONRow переводит объект в строку файла. Числа и bool приводятся к строке.
+func decodeJSONRow(data []byte, num int) (fileRow, error) {
+	row := fileRow{num: num, values: make(map[string]string)}
+
+	var object map[string]any
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&object); err != nil {
+		return row, fmt.Errorf("invalid json object: %v", err)
+	}
+	if object == nil {
+		return row, errors.New("row is not a json object")
+	}
+
+	for key, value := range object {
+		switch v := value.(type) {
+		case nil:
+			row.values[columnKey(key)] = ""
+		case string:
+			row.values[columnKey(key)] = v
+		case json.Number:
+			row.values[columnKey(key)] = v.String()
+		case bool:
+			row.values[columnKey(key)] = strconv.FormatBool(v)
+		default:
+			return row, fmt.Errorf("field %q: nested values are not supported", key)
+		}
+	}
+
+	return row, nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// columnKey - колонки файла сравниваются без учета регистра и лишних пробелов.
+func columnKey(column string) string {
+	return strings.ToLower(strings.Join(strings.Fields(column), " "))
+}
@@ -0,0 +1,184 @@
+package motoparser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+var dealerColumns = map[string]string{
+	"external_id": "Артикул",
+	"name": "Наименование",
+	"year": "Год",
+	"mileage": "Пробег",
+	"engine_size": "Объем",
+	"moto_type": "Класс",
+	"price": "Цена",
+	"location": "Салон",
+	"source_url": "Ссылка",
+}
+
+func newTestFileParser(t *testing.T, cfg SourceConfig) usecase.MotoParser {
+	t.Helper()
+
+	brands, err := LoadBrandDictionary(brandsPath)
+	if err != nil {
+		t.Fatalf("load brands error: %v", err)
+	}
+	classes, err := LoadClassMapping(classesPath)
+	if err != nil {
+		t.Fatalf("load moto classes error: %v", err)
+	}
+
+	cfg.Name, cfg.Kind = "dealer", KindFile
+	parser, err := NewParser(cfg, WithBrands(brands), WithClasses(classes))
+	if err != nil {
+		t.Fatalf("create file parser error: %v", err)
+	}
+
+	return parser
+}
+
+func TestFileParser_GetAllMoto(t *testing.T) {
+	tests := []struct {
+		file string
+		wantNames []string
+		wantRejected map[int]string // строка -> часть причины
+	}{
+		{
+			file: "dealer.csv",
+			wantNames: []string{"Honda CB650R", "Ямаха MT-09 Tracer", "Zero SR/F"},
+			wantRejected: map[int]string{
+				4: "year 1850 is out of range",
+				5: "name is empty",
+				6: "duplicate of row 2",
+				8: `invalid mileage "много"`,
+			},
+		},
+		{
+			file: "dealer.json",
+			wantNames: []string{"Honda CB650R", "Ямаха MT-09 Tracer"},
+			wantRejected: map[int]string{
+				3: "invalid json object",
+				4: `invalid url "ftp://dealer.test/a-3"`,
+			},
+		},
+		{
+			file: "dealer.ndjson",
+			wantNames: []string{"Honda CB650R", "Ямаха MT-09 Tracer"},
+			wantRejected: map[int]string{
+				4: "invalid json object",
+				5: "nested values are not supported",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			parser := newTestFileParser(t, SourceConfig{
+				Path: filepath.Join("testdata", "files", tt.file),
+				Columns: dealerColumns,
+			})
+
+			motos, report, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{})
+			if err != nil {
+				t.Fatalf("get all moto error: %v", err)
+			}
+
+			var names []string
+			for _, m := range motos {
+				names = append(names, m.Name)
+			}
+			if strings.Join(names, "|") != strings.Join(tt.wantNames, "|") {
+				t.Errorf("names = %q, want %q", names, tt.wantNames)
+			}
+
+			if report.CardsFound != len(tt.wantNames)+len(tt.wantRejected) || report.CardsRejected != len(tt.wantRejected) {
+				t.Errorf("cards found/rejected = %d/%d", report.CardsFound, report.CardsRejected)
+			}
+			for _, rejected := range report.RejectedCards {
+				want, ok := tt.wantRejected[rejected.Index]
+				if !ok || !strings.Contains(rejected.Reason, want) {
+					t.Errorf("row %d rejected with %q, want %q", rejected.Index, rejected.Reason, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileParser_ParseRow(t *testing.T) {
+	parser := newTestFileParser(t, SourceConfig{
+		Path: filepath.Join("testdata", "files", "dealer.csv"),
+		Columns: dealerColumns,
+	})
+
+	motos, report, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{})
+	if err != nil || len(motos) != 3 {
+		t.Fatalf("get all moto: %d motos, error: %v", len(motos), err)
+	}
+
+	yamaha := motos[1]
+	if yamaha.ExternalID != "dealer:A-2" || yamaha.Brand != "Yamaha" || yamaha.Model != "MT-09" || yamaha.Trim != "Tracer" {
+		t.Errorf("unexpected identity: %q %q %q %q", yamaha.ExternalID, yamaha.Brand, yamaha.Model, yamaha.Trim)
+	}
+	if yamaha.Year != 2019 || yamaha.Mileage != 12875 || yamaha.MileageUnit != domain.MileageUnitMiles || yamaha.EngineSize != 900 {
+		t.Errorf("unexpected specs: year %d, mileage %d %s, engine %d", yamaha.Year, yamaha.Mileage, yamaha.MileageUnit, yamaha.EngineSize)
+	}
+	if yamaha.Price != 990000 || yamaha.PriceStatus != domain.PriceStatusFrom || yamaha.Class != domain.MotoClassSportTouring {
+		t.Errorf("unexpected price/class: %d %s %s", yamaha.Price, yamaha.PriceStatus, yamaha.Class)
+	}
+
+	if zero := motos[2]; zero.Powertrain != domain.PowertrainElectric || zero.MotorPowerKW != 82 {
+		t.Errorf("unexpected electric moto: %s %v", zero.Powertrain, zero.MotorPowerKW)
+	}
+	if report.UnknownLabels["склад"] != 1 || report.UnknownMotoTypes["Электромотоцикл"] != 1 {
+		t.Errorf("unexpected unknown columns/types: %v %v", report.UnknownLabels, report.UnknownMotoTypes)
+	}
+}
+
+func TestFileParser_UploadedFile(t *testing.T) {
+	parser := newTestFileParser(t, SourceConfig{Columns: dealerColumns})
+
+	// Без файла и без пути в конфиге разбирать нечего
+	if _, _, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{}); !errors.Is(err, domain.InvalidInput) {
+		t.Errorf("expected invalid input without file, got %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "files", "dealer.ndjson"))
+	if err != nil {
+		t.Fatalf("read file error: %v", err)
+	}
+
+	motos, _, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{File: data, FileName: "upload.ndjson"})
+	if err != nil || len(motos) != 2 {
+		t.Errorf("get uploaded motos: %d motos, error: %v", len(motos), err)
+	}
+
+	// Формат определяется по расширению, а колонки проверяются до разбора строк
+	_, _, err = parser.GetAllMoto(context.Background(), domain.ParseOptions{File: []byte("a,b\n1,2\n"), FileName: "upload.csv"})
+	if !errors.Is(err, domain.InvalidInput) || !strings.Contains(err.Error(), "Наименование") {
+		t.Errorf("expected missing name column error, got %v", err)
+	}
+	_, _, err = parser.GetAllMoto(context.Background(), domain.ParseOptions{File: data, FileName: "upload.xlsx"})
+	if !errors.Is(err, domain.InvalidInput) {
+		t.Errorf("expected unknown format error, got %v", err)
+	}
+}
+
+func TestNewFileParser_Validation(t *testing.T) {
+	_, err := NewParser(SourceConfig{
+		Name: "dealer",
+		Kind: KindFile,
+		Format: "xlsx",
+		Columns: map[string]string{"horsepower": "Мощность"},
+	})
+	if err == nil || !strings.Contains(err.Error(), `unknown field "horsepower"`) || !strings.Contains(err.Error(), `unknown format "xlsx"`) {
+		t.Errorf("expected validation errors, got %v", err)
+	}
+}
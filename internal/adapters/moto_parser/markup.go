@@ -0,0 +1,204 @@
+package motoparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Поля мотоцикла, на которые можно сопоставить подписи из разметки.
+const (
+	fieldYear = "year"
+	fieldMileage = "mileage"
+	fieldEngineSize = "engine_size"
+	fieldMotoType = "moto_type"
+	fieldLocation = "location"
+
+	fieldHorsepower = "horsepower"
+	fieldWeight = "weight"
+	fieldColor = "color"
+	fieldTransmission = "transmission"
+	fieldABS = "abs"
+)
+
+var (
+	cardFields = []string{fieldYear, fieldMileage, fieldEngineSize, fieldMotoType, fieldLocation}
+	detailFields = []string{fieldHorsepower, fieldWeight, fieldColor, fieldTransmission, fieldABS}
+)
+
+/*
+Markup описывает разметку сайта: CSS-селекторы элементов карточки и
+подписи строк характеристик для каждого поля мотоцикла.
+Если сайт переименует класс или подпись, достаточно поправить конфиг.
+Селекторы внутри карточки (title, info, ...) ищутся относительно карточки,
+info_row - относительно info, info_name/info_value - относительно строки.
+*/
+type Markup struct {
+	Card string `json:"card"`
+	Title string `json:"title"`
+	Link string `json:"link"`
+	Image string `json:"image"`
+	Info string `json:"info"`
+	InfoRow string `json:"info_row"`
+	InfoName string `json:"info_name"`
+	InfoValue string `json:"info_value"`
+	Price string `json:"price"`
+	OldPrice string `json:"old_price"` // необязательный, см. parseCardPrice
+
+	// поле -> подписи, под которыми оно встречается на сайте
+	Labels map[string][]string `json:"labels"`
+
+	Detail DetailMarkup `json:"detail"`
+
+	card, title, link, image Selector
+	info, infoRow, infoName, infoValue Selector
+	price, oldPrice Selector
+	cardLabels map[string]string
+}
+
+// DetailMarkup - разметка страницы объявления.
+type DetailMarkup struct {
+	Prop string `json:"prop"`
+	PropName string `json:"prop_name"`
+	PropValue string `json:"prop_value"`
+	Description string `json:"description"`
+
+	Labels map[string][]string `json:"labels"`
+
+	prop, propName, propValue, description Selector
+	detailLabels map[string]string
+}
+
+func LoadMarkup(path string) (Markup, error) {
+	var m Markup
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, fmt.Errorf("read markup config error: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("decode markup config %s error: %w", path, err)
+	}
+
+	if err := m.init(); err != nil {
+		return m, fmt.Errorf("invalid markup config %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// init проверяет конфиг, компилирует селекторы и строит обратные индексы "подпись -> поле".
+func (m *Markup) init() error {
+	var errs []error
+
+	selectors := []struct {
+		name string
+		src string
+		dst *Selector
+	}{
+		{"card", m.Card, &m.card},
+		{"title", m.Title, &m.title},
+		{"link", m.Link, &m.link},
+		{"image", m.Image, &m.image},
+		{"info", m.Info, &m.info},
+		{"info_row", m.InfoRow, &m.infoRow},
+		{"info_name", m.InfoName, &m.infoName},
+		{"info_value", m.InfoValue, &m.infoValue},
+		{"price", m.Price, &m.price},
+		{"detail.prop", m.Detail.Prop, &m.Detail.prop},
+		{"detail.prop_name", m.Detail.PropName, &m.Detail.propName},
+		{"detail.prop_value", m.Detail.PropValue, &m.Detail.propValue},
+		{"detail.description", m.Detail.Description, &m.Detail.description},
+	}
+	for _, s := range selectors {
+		if strings.TrimSpace(s.src) == "" {
+			errs = append(errs, fmt.Errorf("%s is empty", s.name))
+			continue
+		}
+
+		sel, err := CompileSelector(s.src)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			continue
+		}
+		*s.dst = sel
+	}
+
+	if strings.TrimSpace(m.OldPrice) != "" {
+		sel, err := CompileSelector(m.OldPrice)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("old_price: %w", err))
+		}
+		m.oldPrice = sel
+	}
+
+	var err error
+	m.cardLabels, err = buildLabelIndex("labels", m.Labels, cardFields)
+	errs = append(errs, err)
+
+	m.Detail.detailLabels, err = buildLabelIndex("detail.labels", m.Detail.Labels, detailFields)
+	errs = append(errs, err)
+
+	return errors.Join(errs...)
+}
+
+// fieldByLabel возвращает поле карточки по подписи строки.
+func (m Markup) fieldByLabel(label string) (string, bool) {
+	field, ok := m.cardLabels[normalizeLabel(label)]
+	return field, ok
+}
+
+func (m DetailMarkup) fieldByLabel(label string) (string, bool) {
+	field, ok := m.detailLabels[normalizeLabel(label)]
+	return field, ok
+}
+
+func buildLabelIndex(
+	name string,
+	labels map[string][]string,
+	fields []string,
+) (map[string]string, error) {
+	var errs []error
+	index := make(map[string]string)
+
+	known := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		known[field] = true
+		if len(labels[field]) == 0 {
+			errs = append(errs, fmt.Errorf("%s: no labels for field %q", name, field))
+		}
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(labels)) {
+		if !known[field] {
+			errs = append(errs, fmt.Errorf("%s: unknown field %q", name, field))
+			continue
+		}
+
+		for _, label := range labels[field] {
+			key := normalizeLabel(label)
+			if key == "" {
+				errs = append(errs, fmt.Errorf("%s: empty label for field %q", name, field))
+				continue
+			}
+			if other, ok := index[key]; ok && other != field {
+				errs = append(errs, fmt.Errorf("%s: label %q used for both %q and %q", name, label, other, field))
+				continue
+			}
+			index[key] = field
+		}
+	}
+
+	return index, errors.Join(errs...)
+}
+
+// Подписи сравниваем без учета регистра, лишних пробелов и двоеточия в конце.
+func normalizeLabel(label string) string {
+	label = strings.Join(strings.Fields(label), " ")
+	return strings.ToLower(strings.TrimSuffix(label, ":"))
+}
@@ -1,10 +1,16 @@
 package motoparser
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 	"unicode"
-	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -12,24 +18,149 @@ import (
 	"github.com/vvetta/electoral_system/internal/usecase"
 	
 	"golang.org/x/net/html"
+	"golang.org/x/sync/errgroup"
 )
 
 type motoParser struct {
 	url string
-	prodCardClassName string
+	markup Markup
 	maxPageCount int
+	workers int
+	fetcher *fetcher
+	snapshotDir string
+	dictionaries
 }
 
+// dictionaries - словари, по которым и парсер сайта, и файловый парсер
+// раскладывают заголовок на марку и модель и определяют класс.
+type dictionaries struct {
+	brands *BrandDictionary
+	classes *ClassMapping
+}
+
+/*
+parserOptions - то, что задают Option. Парсер сайта берет все настройки,
+файловому нужны только словари.
+*/
+type parserOptions struct {
+	timeout time.Duration
+	workers int
+	rateInterval time.Duration
+	maxRetries int
+	retryBaseDelay time.Duration
+	snapshotDir string
+	dictionaries
+}
+
+func newParserOptions(opts []Option) parserOptions {
+	o := parserOptions{
+		timeout: defaultTimeout,
+		workers: defaultWorkers,
+		rateInterval: defaultRateInterval,
+		maxRetries: defaultMaxRetries,
+		retryBaseDelay: defaultRetryBaseDelay,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Option меняет настройки парсера.
+type Option func(o *parserOptions)
+
+// WithTimeout задает таймаут одного http-запроса.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *parserOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithWorkers задает количество страниц, которые качаются одновременно.
+func WithWorkers(workers int) Option {
+	return func(o *parserOptions) {
+		if workers > 0 {
+			o.workers = workers
+		}
+	}
+}
+
+// WithRateInterval задает минимальный интервал между запросами к одному хосту.
+// 0 отключает ограничение.
+func WithRateInterval(interval time.Duration) Option {
+	return func(o *parserOptions) {
+		o.rateInterval = interval
+	}
+}
+
+// WithRetries задает количество повторов и начальную задержку между ними.
+func WithRetries(maxRetries int, baseDelay time.Duration) Option {
+	return func(o *parserOptions) {
+		o.maxRetries = maxRetries
+		o.retryBaseDelay = baseDelay
+	}
+}
+
+// WithSnapshotDir включает архив сырых страниц в каталоге dir, см. snapshot.go.
+// Из этого же каталога берутся снимки для ParseOptions.Snapshot.
+func WithSnapshotDir(dir string) Option {
+	return func(o *parserOptions) {
+		o.snapshotDir = dir
+	}
+}
+
+// WithBrands включает разбор заголовков на марку, модель и комплектацию.
+func WithBrands(brands *BrandDictionary) Option {
+	return func(o *parserOptions) {
+		o.brands = brands
+	}
+}
+
+// WithClasses задает таблицу синонимов классов, см. ClassMapping.
+func WithClasses(classes *ClassMapping) Option {
+	return func(o *parserOptions) {
+		o.classes = classes
+	}
+}
+
+/*
+NewMotoParser создает парсер каталога.
+markupPath - путь к json-конфигу с классами элементов и подписями характеристик,
+конфиг проверяется сразу, чтобы ошибка в нем всплыла при старте, а не при обходе.
+*/
 func NewMotoParser(
 	url string,
-	prodCardClassName string,
+	markupPath string,
 	maxPageCount int,
-) usecase.MotoParser {
+	opts ...Option,
+) (usecase.MotoParser, error) {
+	markup, err := LoadMarkup(markupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	o := newParserOptions(opts)
+	f := newFetcher()
+	f.client.Timeout = o.timeout
+	f.rateInterval = o.rateInterval
+	f.maxRetries = o.maxRetries
+	f.retryBaseDelay = o.retryBaseDelay
+
 	return &motoParser{
 		url: url,
-		prodCardClassName: prodCardClassName,
-		maxPageCount: maxPageCount,	
-	}
+		markup: markup,
+		maxPageCount: maxPageCount,
+		workers: o.workers,
+		fetcher: f,
+		snapshotDir: o.snapshotDir,
+		dictionaries: o.dictionaries,
+	}, nil
+}
+
+// Скачанная и разобранная страница каталога
+type catalogPage struct {
+	url *url.URL
+	node *html.Node
 }
 
 /*
@@ -37,240 +168,460 @@ func NewMotoParser(
 На сайте нет никакой защиты от ботов и простой парсинг html
 
 Пагинация на сайте реализована простым увеличением страницы
-через кнопку "Показать еще". Для того чтобы спарсить все
-мотоциклы было решено использвать offset.
+через кнопку "Показать еще". Сейчас ?nav-catalog=page-N отдает все
+карточки предыдущих страниц плюс новые, но полагаться на это нельзя:
+режим определяется сравнением ключей объявлений между страницами,
+см. getMotosFromCatalog.
+
+Страницы качаются пачками по p.workers штук параллельно, а разбираются
+строго по порядку.
 */
-func (p *motoParser) GetAllMoto() ([]domain.Moto, error) {
+func (p *motoParser) GetAllMoto(
+	ctx context.Context,
+	opts domain.ParseOptions,
+) ([]domain.Moto, domain.ParseReport, error) {
+	return collectMotos(ctx, opts, p.StreamAllMoto)
+}
+
+// StreamAllMoto отправляет мотоциклы в out сразу после разбора очередной страницы.
+// Канал out не закрывается, это делает вызывающий после возврата.
+func (p *motoParser) StreamAllMoto(
+	ctx context.Context,
+	opts domain.ParseOptions,
+	out chan<- domain.Moto,
+) (domain.ParseReport, error) {
 	log.Print("MotoParser: Start!")	
-	var motos []domain.Moto
 
-	var offset int = 0
-	for i := 1; i <= p.maxPageCount; i++ {
-		url := p.url + "?nav-catalog=page-" + strconv.Itoa(i)
-		log.Print("MotoParser: parsing page: ", url)
+	rep := newReportBuilder()
+	rep.progress = opts.NotifyProgress
+
+	src, closeSrc, err := p.openPageSource(opts, rep)
+	if err != nil {
+		log.Print("MotoParser: open page source error: ", err)
+		return rep.finish(err), err
+	}
+	defer closeSrc()
+
+	if err := p.getMotosFromCatalog(ctx, opts, src, rep, out); err != nil {
+		return rep.finish(err), err
+	}
+
+	log.Print("MotoParser: End!")
+	return rep.finish(nil), nil
+}
+
+/*
+openPageSource выбирает, откуда брать страницы в этом обходе:
+из снимка (opts.Snapshot), из сети с записью в архив (если задан snapshotDir)
+или просто из сети. Если архив не удалось создать, обход идет без него.
+*/
+func (p *motoParser) openPageSource(
+	opts domain.ParseOptions,
+	rep *reportBuilder,
+) (pageFetcher, func(), error) {
+	if opts.File != nil {
+		return nil, nil, fmt.Errorf("%w: source does not accept files", domain.InvalidInput)
+	}
+
+	if opts.Snapshot != "" {
+		if p.snapshotDir == "" {
+			return nil, nil, fmt.Errorf("%w: snapshot dir is not configured", domain.SnapshotNotFound)
+		}
 
-		motosFromPage, err := p.getMotosFromPage(url, offset); 
+		snapshot, err := openSnapshot(p.snapshotDir, opts.Snapshot)
 		if err != nil {
-			log.Print("MotoParser: get motos from page error: ", err)
-			return nil, fmt.Errorf("%w: Ошибка получения мотоциклов со страницы: %s", domain.ParseMotoError, url)
+			return nil, nil, err
 		}
+		log.Print("MotoParser: replay snapshot: ", snapshot.id)
+		rep.snapshot(snapshot.id)
+		return snapshot, func() {}, nil
+	}
 
-		if len(motosFromPage) == 0 {
-			log.Print("MotoParser: find 0 moto from page. Stop parsing...")
-			break
+	if p.snapshotDir != "" {
+		recorder, err := newSnapshotRecorder(p.snapshotDir, p.fetcher)
+		if err != nil {
+			log.Print("MotoParser: snapshot disabled for this run: ", err)
+			return p.fetcher, func() {}, nil
 		}
-		log.Printf("MotoParser: find %d moto from page", len(motosFromPage))
+		log.Print("MotoParser: write snapshot: ", recorder.id)
+		rep.snapshot(recorder.id)
+		return recorder, recorder.close, nil
+	}
+
+	return p.fetcher, func() {}, nil
+}
 
-		motos = append(motos, motosFromPage...)
-		offset += len(motosFromPage)
-		log.Print("MotoParser: motoFromPage succsessfully appended to motos")	
+// CheckSnapshot проверяет, что снимок id есть и его можно разобрать.
+func (p *motoParser) CheckSnapshot(id string) error {
+	if p.snapshotDir == "" {
+		return fmt.Errorf("%w: snapshot dir is not configured", domain.SnapshotNotFound)
 	}
 
-	log.Print("MotoParser: End!")
-	return motos, nil
+	_, err := openSnapshot(p.snapshotDir, id)
+	return err
 }
 
-func (p *motoParser) getMotosFromPage(url string, offset int) ([]domain.Moto, error) {
-	log.Print("MotoParser-getMotosFromPage: Start!")	
+/*
+getMotosFromCatalog обходит страницы, пока очередная страница не добавит
+ни одного нового объявления (или пока не кончится maxPageCount).
+Каждая карточка идентифицируется ключом (ссылка на объявление), поэтому
+без разницы, отдает ли сайт накопительно все предыдущие карточки или
+только новую страницу, и какого она размера: повторы просто пропускаются.
+*/
+func (p *motoParser) getMotosFromCatalog(
+	ctx context.Context,
+	opts domain.ParseOptions,
+	src pageFetcher,
+	rep *reportBuilder,
+	out chan<- domain.Moto,
+) error {
+	seen := make(map[string]bool)
+	for first := 1; first <= p.maxPageCount; first += p.workers {
+		last := min(first+p.workers-1, p.maxPageCount)
+
+		pages, err := p.fetchPages(ctx, src, first, last, rep)
+		if err != nil {
+			log.Print("MotoParser: fetch pages error: ", err)
+			return err
+		}
 
-	client := &http.Client{}
+		for i, page := range pages {
+			motosFromPage, newCards, repeatedCards := p.getMotosFromHtml(page.node, page.url, seen, rep)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Print("MotoParser-getMotosFromPage: create request error: ", err)
-		return nil, err
+			if newCards == 0 {
+				log.Print("MotoParser: find 0 new moto from page. Stop parsing...")
+				return nil
+			}
+			log.Printf("MotoParser: find %d moto from page", len(motosFromPage))
+
+			// Режим определяем по второй странице: если на ней есть карточки
+			// с первой, значит сайт отдает каталог накопительно
+			if first+i == 2 {
+				mode := domain.PaginationPaged
+				if repeatedCards > 0 {
+					mode = domain.PaginationCumulative
+				}
+				log.Print("MotoParser: pagination mode: ", mode)
+				rep.paginationMode(mode)
+			}
+
+			if opts.WithDetails {
+				if err := p.enrichWithDetails(ctx, src, motosFromPage, rep); err != nil {
+					log.Print("MotoParser: enrich with details error: ", err)
+					return err
+				}
+			}
+
+			if first+i == 1 {
+				if err := opts.CheckFirstPage(rep.preview(motosFromPage)); err != nil {
+					log.Print("MotoParser: first page check failed: ", err)
+					return err
+				}
+			}
+
+			if err := emitMotos(ctx, motosFromPage, rep, out); err != nil {
+				return err
+			}
+		}
 	}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Print("MotoParser-getMotosFromPage: client.do error: ", err)
-		return nil, err	
+	log.Print("MotoParser: maxPageCount reached, catalog may be truncated")
+	rep.truncated()
+	return nil
+}
+
+func (p *motoParser) pageURL(page int) string {
+	return p.url + "?nav-catalog=page-" + strconv.Itoa(page)
+}
+
+// fetchPages параллельно качает страницы [first, last] и возвращает их по порядку.
+// Ошибка любой страницы отменяет остальные запросы.
+// Страница, которой нет в проигрываемом снимке или на сайте (404 дальше первой
+// страницы - каталог кончился раньше пачки), остается пустой - на ней обход и закончится.
+func (p *motoParser) fetchPages(
+	ctx context.Context,
+	src pageFetcher,
+	first, last int,
+	rep *reportBuilder,
+) ([]catalogPage, error) {
+	pages := make([]catalogPage, last-first+1)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(p.workers)
+
+	for i := range pages {
+		pageNum := first + i
+		pageURL := p.pageURL(pageNum)
+		g.Go(func() error {
+			log.Print("MotoParser: parsing page: ", pageURL)
+
+			page, err := p.fetchPage(gctx, src, pageURL)
+			if errors.Is(err, errPageNotInSnapshot) {
+				log.Print("MotoParser: page is not in snapshot: ", pageURL)
+				return nil
+			}
+			if pageNum > 1 && isNotFound(err) {
+				log.Print("MotoParser: page not found, end of catalog: ", pageURL)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			rep.pageFetched()
+
+			pages[i] = page
+			return nil
+		})
 	}
 
-	if resp.StatusCode >= 400 {
-		log.Print("MotoParser-getMotosFromPage: resp status code >= 400")
-		return nil, domain.ParseMotoError
+	if err := g.Wait(); err != nil {
+		return nil, err
 	}
 
-	defer resp.Body.Close()
+	return pages, nil
+}
 
-	pageNode, err := html.Parse(resp.Body)
+func (p *motoParser) fetchPage(
+	ctx context.Context,
+	src pageFetcher,
+	pageURL string,
+) (catalogPage, error) {
+	baseURL, err := url.Parse(pageURL)
 	if err != nil {
-		log.Print("MotoParser-getMotosFromPage: html.parse error: ", err)
-		return nil, err	
+		log.Print("MotoParser-fetchPage: parse page url error: ", err)
+		return catalogPage{}, err
 	}
 
-	motosFromPage, err := p.getMotosFromHtml(pageNode, offset)
+	body, err := src.fetch(ctx, pageURL)
 	if err != nil {
-		log.Print("MotoParser-getMotosFromPage: get motos from html error: ", err)
-		return nil, err
+		log.Print("MotoParser-fetchPage: fetch error: ", err)
+		return catalogPage{}, err
 	}
 
-	log.Print("MotoParser-getMotosFromPage: End!")
-	return motosFromPage, nil
+	pageNode, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		log.Print("MotoParser-fetchPage: html.parse error: ", err)
+		return catalogPage{}, err
+	}
+
+	return catalogPage{url: baseURL, node: pageNode}, nil
 }
 
-func (p *motoParser) getMotosFromHtml(pageNode *html.Node, offset int) ([]domain.Moto, error) {
+// getMotosFromHtml разбирает карточки страницы, которых еще нет в seen.
+// Возвращает мотоциклы, число новых карточек (включая отбракованные) и число повторов.
+func (p *motoParser) getMotosFromHtml(
+	pageNode *html.Node,
+	baseURL *url.URL,
+	seen map[string]bool,
+	rep *reportBuilder,
+) ([]domain.Moto, int, int) {
 	log.Print("MotoParser-getMotosFromHtml: Start!")
 	
-	var cards []*html.Node
-
-	var walk func(*html.Node)
-	walk = func(n *html.Node) {
-		if n == nil {
-			return
-		}
-
-		if n.Type == html.ElementNode {
-			for _, attr := range n.Attr {
-				if attr.Key == "class" {
-					classes := strings.Fields(attr.Val)
-					for _, value := range classes {
-						if value == p.prodCardClassName {
-							cards = append(cards, n)
-							break
-						}
-					}
-					break
-				}
-			}
-		}
-
-		for child := n.FirstChild; child != nil; child = child.NextSibling {
-    	walk(child)
-    }
-	}
-
-	walk(pageNode)
+	cards := p.markup.card.MatchAll(pageNode)
 
 	if len(cards) == 0 {
 		log.Print("MotoParser-getMotosFromHtml: no cards found")
-		return nil, nil // или domain.ParseMotoError — по твоему дизайну
-	}
-
-	if offset >= len(cards) {
-		log.Print("MotoParser-getMotosFromHtml: offset >= len(cards), no new motos")
-		return nil, nil
+		return nil, 0, 0
 	}
 
-	start := offset
 	var motos []domain.Moto
+	var newCards, repeatedCards int
 	
-	for i := start; i < len(cards); i++ {
-		card := cards[i]
+	for i, card := range cards {
+		key := cardIdentity(card, p.markup, baseURL)
+		if seen[key] {
+			repeatedCards++
+			continue
+		}
+		seen[key] = true
+		newCards++
 
-		moto, err := parseMotoCard(card)
+		moto, err := parseMotoCard(card, p.markup, baseURL, rep)
 		if err != nil {
 			log.Printf("MotoParser-getMotosFromHtml: parse card #%d error: %v", i, err)
+			rep.cardRejected(baseURL.String(), i, err)
 			continue
 		}
+		moto.Brand, moto.Model, moto.Trim = p.brands.Split(moto.Name)
+		moto.Class = p.classes.Classify(moto.MotoType)
+		if moto.Class == "" && moto.MotoType != "" {
+			rep.unknownMotoType(moto.MotoType)
+		}
 
 		motos = append(motos, moto)
 	}
+	rep.cardsFound(newCards)
 
-	log.Printf("MotoParser-getMotosFromHtml: parsed %d motos", len(motos))
+	log.Printf("MotoParser-getMotosFromHtml: parsed %d motos, new cards: %d, repeated: %d", len(motos), newCards, repeatedCards)
 	log.Print("MotoParser-getMotosFromHtml: End!")
-	return motos, nil
+	return motos, newCards, repeatedCards
 }
 
-func parseMotoCard(card *html.Node) (domain.Moto, error) {
+/*
+cardIdentity - ключ карточки для сравнения страниц между собой.
+Обычно это ссылка на объявление. Если ссылки нет (битая карточка),
+берется хеш текста карточки, чтобы она не считалась новой на каждой странице.
+*/
+func cardIdentity(card *html.Node, markup Markup, baseURL *url.URL) string {
+	if link := cardLink(card, markup, baseURL); link != "" {
+		return link
+	}
+
+	text := strings.Join(strings.Fields(getText(card)), " ")
+	sum := sha256.Sum256([]byte(text))
+	return "text:" + hex.EncodeToString(sum[:])
+}
+
+// cardLink ищет ссылку на объявление: сначала в заголовке, потом в любом месте карточки.
+func cardLink(card *html.Node, markup Markup, baseURL *url.URL) string {
+	if title := markup.title.MatchFirst(card); title != nil {
+		if href := getAttr(markup.link.MatchFirst(title), "href"); href != "" {
+			return resolveURL(baseURL, href)
+		}
+	}
+
+	return resolveURL(baseURL, getAttr(markup.link.MatchFirst(card), "href"))
+}
+
+func parseMotoCard(
+	card *html.Node,
+	markup Markup,
+	baseURL *url.URL,
+	rep *reportBuilder,
+) (domain.Moto, error) {
 	var m domain.Moto
 
 	if card == nil {
 		return m, fmt.Errorf("card is nil")
 	}
 
-	// 1. Находим div.slider-card__title
-  titleNodes := getNodesByClass(card, "slider-card__title")
+	// 1. Находим заголовок карточки
+  titleNodes := markup.title.MatchAll(card)
   if len(titleNodes) == 0 {
-      return m, fmt.Errorf("slider-card__title not found")
+      return m, fmt.Errorf("%s not found", markup.Title)
   }
 
     // 2. Достаём текст (он лежит внутри <a>…, но можно просто собрать весь текст внутри div)
   motoTitle := strings.TrimSpace(getText(titleNodes[0]))
   m.Name = motoTitle
 
+	m.SourceURL = cardLink(card, markup, baseURL)
+
+	// Фото. На сайте картинки грузятся лениво, поэтому src может быть заглушкой
+	m.ImageURL = resolveURL(baseURL, getImageSrc(markup.image.MatchAll(card)))
+
 	// Блок характеристик
-	infoBlocks := getNodesByClass(card, "slider-card__info")
+	infoBlocks := markup.info.MatchAll(card)
 	if len(infoBlocks) == 0 {
-		return m, fmt.Errorf("slider-card__info not found")
+		return m, fmt.Errorf("%s not found", markup.Info)
 	}
 	info := infoBlocks[0]
 
-	rows := getNodesByClass(info, "slider-card__row")
+	rows := markup.infoRow.MatchAll(info)
 	for _, row := range rows {
-		nameNodes := getNodesByClass(row, "slider-card__info-name")
-		valueNodes := getNodesByClass(row, "slider-card__info-text")
+		nameNodes := markup.infoName.MatchAll(row)
+		valueNodes := markup.infoValue.MatchAll(row)
 		if len(nameNodes) == 0 || len(valueNodes) == 0 {
 			continue
 		}
 
-		name := getText(nameNodes[0])
+		label := getText(nameNodes[0])
+		rep.labelSeen()
+		field, ok := markup.fieldByLabel(label)
+		if !ok {
+			rep.unknownLabel(label)
+			continue
+		}
 		value := getText(valueNodes[0])
 
-		switch name {
-		case "Год":
+		switch field {
+		case fieldYear:
 			if v, err := parseIntFromString(value); err == nil {
 				m.Year = v
 			}
-		case "Пробег ТС":
-			if v, err := parseIntFromString(value); err == nil {
-				m.Mileage = v
+		case fieldMileage:
+			if v, ok := parseMileage(value); ok {
+				m.Mileage = v.km
+				m.MileageOriginal = v.original
+				m.MileageUnit = v.unit
 			}
-		case "Объем Д":
-			if v, err := parseIntFromString(value); err == nil {
-				m.EngineSize = v
+		case fieldEngineSize:
+			if v, ok := parseEngine(value); ok {
+				m.EngineSize = v.cc
+				m.EngineSizeUnit = v.unit
+				m.Powertrain = v.powertrain
+				m.MotorPowerKW = v.powerKW
 			}
-		case "Класс мототехники":
+		case fieldMotoType:
 			m.MotoType = value
-		case "Мотосалон":
+		case fieldLocation:
 			m.Location = value
+			if href := getAttr(markup.link.MatchFirst(valueNodes[0]), "href"); href != "" {
+				m.DealerURL = resolveURL(baseURL, href)
+			}
 		}
 	}
 
-	// Цена
-	priceNodes := getNodesByClass(card, "slider-card__price-title")
-	if len(priceNodes) > 0 {
-		priceText := getText(priceNodes[0]) // типа "1 190 000 р."
-		if v, err := parseIntFromString(priceText); err == nil {
-			m.Price = int64(v)
-		}
+	// Электромотоцикл без строки мощности узнаем по названию или классу
+	if m.Powertrain == "" && isElectric(m) {
+		m.Powertrain = domain.PowertrainElectric
 	}
 
+	// Цена: "1 190 000 р.", "от 1 190 000 р.", "Цена по запросу", со скидкой и без
+	price := parseCardPrice(card, markup)
+	m.Price = price.current
+	m.OldPrice = price.old
+	m.PriceCurrency = price.currency
+	m.PriceStatus = price.status
+
 	return m, nil
 }
 
-func getNodesByClass(root *html.Node, className string) []*html.Node {
-	var result []*html.Node
+func getAttr(n *html.Node, key string) string {
+	if n == nil {
+		return ""
+	}
 
-	var walk func(*html.Node)
-	walk = func(n *html.Node) {
-		if n == nil {
-			return
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return strings.TrimSpace(attr.Val)
 		}
+	}
 
-		if n.Type == html.ElementNode {
-			for _, attr := range n.Attr {
-				if attr.Key == "class" {
-					classes := strings.Fields(attr.Val)
-					for _, c := range classes {
-						if c == className {
-							result = append(result, n)
-							break
-						}
-					}
-					break
-				}
+	return ""
+}
+
+// getImageSrc возвращает первую нормальную картинку из найденных.
+// data-src проверяем раньше src, так как в src у ленивых картинок лежит заглушка.
+func getImageSrc(images []*html.Node) string {
+	for _, img := range images {
+		for _, key := range []string{"data-src", "data-lazy", "src"} {
+			v := getAttr(img, key)
+			if v != "" && !strings.HasPrefix(v, "data:") {
+				return v
 			}
 		}
+	}
 
-		for child := n.FirstChild; child != nil; child = child.NextSibling {
-			walk(child)
-		}
+	return ""
+}
+
+// resolveURL превращает относительную ссылку из разметки в абсолютную.
+func resolveURL(baseURL *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+
+	if baseURL == nil {
+		return refURL.String()
 	}
 
-	walk(root)
-	return result
+	return baseURL.ResolveReference(refURL).String()
 }
 
 func parseIntFromString(s string) (int, error) {
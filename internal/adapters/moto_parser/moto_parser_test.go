@@ -1 +1,463 @@
 package motoparser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// Вместо адреса тестового сервера в golden-файлах пишем этот плейсхолдер,
+// иначе ожидания будут зависеть от случайного порта.
+const serverPlaceholder = "http://catalog.test"
+
+// Тесты гоняются на боевых конфигах разметки, словаря марок и классов, чтобы ловить ошибки в них же.
+const (
+	markupPath = "../../../configs/markup/mr-moto.json"
+	brandsPath = "../../../configs/brands.json"
+	classesPath = "../../../configs/moto_classes.json"
+)
+
+func newTestParser(t *testing.T, url string, maxPageCount int, opts ...Option) usecase.MotoParser {
+	t.Helper()
+
+	brands, err := LoadBrandDictionary(brandsPath)
+	if err != nil {
+		t.Fatalf("load brands error: %v", err)
+	}
+
+	classes, err := LoadClassMapping(classesPath)
+	if err != nil {
+		t.Fatalf("load moto classes error: %v", err)
+	}
+
+	parser, err := NewMotoParser(url, markupPath, maxPageCount, append([]Option{WithBrands(brands), WithClasses(classes)}, opts...)...)
+	if err != nil {
+		t.Fatalf("create moto parser error: %v", err)
+	}
+
+	return parser
+}
+
+/*
+newCatalogServer отдает страницы каталога из testdata/<dir>.
+Страница выбирается по параметру nav-catalog=page-N, как на mr-moto.ru.
+Если файла для страницы нет - отдается пустой каталог.
+Страницы объявлений /catalog/mototsikly/<slug>/ берутся из testdata/<dir>/detail/<slug>.html.
+*/
+func newCatalogServer(t *testing.T, dir string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slug := strings.Trim(strings.TrimPrefix(r.URL.Path, "/catalog/mototsikly/"), "/"); slug != "" {
+			body, err := os.ReadFile(filepath.Join("testdata", dir, "detail", slug+".html"))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(body)
+			return
+		}
+
+		page := r.URL.Query().Get("nav-catalog")
+		if page == "" {
+			page = "page-1"
+		}
+
+		body, err := os.ReadFile(filepath.Join("testdata", dir, page+".html"))
+		if err != nil {
+			body = []byte("<html><body><div class=\"page-card\"></div></body></html>")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(body)
+	}))
+}
+
+func TestMotoParser_GetAllMoto_Golden(t *testing.T) {
+	tests := []struct {
+		name         string
+		dir          string
+		maxPageCount int
+		opts         domain.ParseOptions
+	}{
+		{name: "pagination", dir: "pagination", maxPageCount: 10},
+		{name: "pagination limited by maxPageCount", dir: "pagination", maxPageCount: 1},
+		{name: "paged pagination", dir: "pagination_paged", maxPageCount: 10},
+		{name: "empty page", dir: "empty", maxPageCount: 10},
+		{name: "malformed cards", dir: "malformed_cards", maxPageCount: 10},
+		{name: "missing slider-card__info", dir: "missing_info", maxPageCount: 10},
+		{name: "odd price strings", dir: "odd_prices", maxPageCount: 10},
+		{name: "units", dir: "units", maxPageCount: 10},
+		{name: "details", dir: "details", maxPageCount: 1, opts: domain.ParseOptions{WithDetails: true}},
+		{name: "details disabled", dir: "details", maxPageCount: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newCatalogServer(t, tt.dir)
+			defer srv.Close()
+
+			parser := newTestParser(t, srv.URL+"/catalog/mototsikly/", tt.maxPageCount, WithRateInterval(0))
+
+			motos, _, err := parser.GetAllMoto(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("get all moto error: %v", err)
+			}
+
+			// Время разбора деталей каждый раз разное, в golden-файл его не пишем
+			for i := range motos {
+				motos[i].DetailsParsedAt = nil
+			}
+
+			got, err := json.MarshalIndent(motos, "", "  ")
+			if err != nil {
+				t.Fatalf("marshal motos error: %v", err)
+			}
+			got = []byte(strings.ReplaceAll(string(got), srv.URL, serverPlaceholder) + "\n")
+
+			goldenName := strings.ReplaceAll(tt.name, " ", "_") + ".golden.json"
+			goldenPath := filepath.Join("testdata", tt.dir, goldenName)
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatalf("write golden file error: %v", err)
+				}
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("read golden file error: %v (run with -update to create)", err)
+			}
+
+			if string(got) != string(want) {
+				t.Errorf("motos mismatch with %s\ngot:\n%s\nwant:\n%s", goldenPath, got, want)
+			}
+		})
+	}
+}
+
+func TestMotoParser_GetAllMoto_RetriesServerErrors(t *testing.T) {
+	catalog := newCatalogServer(t, "missing_info")
+	defer catalog.Close()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Первые два запроса падают с 503, потом сервер "оживает"
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		catalog.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	parser := newTestParser(
+		t,
+		srv.URL+"/catalog/mototsikly/",
+		1,
+		WithRateInterval(0),
+		WithRetries(3, time.Millisecond),
+	)
+
+	motos, _, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("get all moto error: %v", err)
+	}
+
+	if len(motos) != 1 {
+		t.Errorf("expected 1 moto after retries, got %d", len(motos))
+	}
+}
+
+func TestMotoParser_GetAllMoto_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	parser := newTestParser(
+		t,
+		srv.URL+"/catalog/mototsikly/",
+		1,
+		WithRateInterval(0),
+		WithRetries(3, time.Millisecond),
+	)
+
+	if _, _, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{}); !errors.Is(err, domain.ParseMotoError) {
+		t.Errorf("expected ParseMotoError, got %v", err)
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 request for 404, got %d", calls.Load())
+	}
+}
+
+func TestMotoParser_GetAllMoto_NotFoundPastLastPage(t *testing.T) {
+	// Страниц дальше последней на сайте нет - 404, а не пустой каталог
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := os.ReadFile(filepath.Join("testdata", "pagination_paged", r.URL.Query().Get("nav-catalog")+".html"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(body)
+	}))
+	defer srv.Close()
+
+	// Пачка из 5 страниц заходит за конец каталога
+	parser := newTestParser(t, srv.URL+"/catalog/mototsikly/", 100, WithRateInterval(0), WithWorkers(5))
+
+	motos, report, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("get all moto error: %v", err)
+	}
+	if len(motos) != 4 || report.PagesFetched != 3 || report.Truncated {
+		t.Errorf("got %d motos, %d pages, truncated %v; want 4 motos from 3 pages", len(motos), report.PagesFetched, report.Truncated)
+	}
+}
+
+func TestMotoParser_GetAllMoto_Cancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	parser := newTestParser(t, srv.URL+"/catalog/mototsikly/", 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, _, err := parser.GetAllMoto(ctx, domain.ParseOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("crawl was not cancelled in time: %s", elapsed)
+	}
+}
+
+func TestMotoParser_GetAllMoto_Report(t *testing.T) {
+	srv := newCatalogServer(t, "malformed_cards")
+	defer srv.Close()
+
+	parser := newTestParser(t, srv.URL+"/catalog/mototsikly/", 1, WithRateInterval(0))
+
+	motos, report, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("get all moto error: %v", err)
+	}
+
+	if report.PagesFetched != 1 {
+		t.Errorf("PagesFetched = %d, want 1", report.PagesFetched)
+	}
+	if report.CardsFound != 4 || report.CardsParsed != len(motos) || report.CardsRejected != 2 {
+		t.Errorf("cards found/parsed/rejected = %d/%d/%d, want 4/%d/2",
+			report.CardsFound, report.CardsParsed, report.CardsRejected, len(motos))
+	}
+	if len(report.RejectedCards) != 2 || !strings.Contains(report.RejectedCards[0].Reason, "slider-card__title") {
+		t.Errorf("unexpected rejected cards: %+v", report.RejectedCards)
+	}
+	if report.UnknownLabels["неизвестная строка"] != 1 {
+		t.Errorf("unknown label not reported: %v", report.UnknownLabels)
+	}
+	if report.EmptyFields["mileage"] != 1 || report.EmptyFields["location"] != 1 {
+		t.Errorf("unexpected empty fields: %v", report.EmptyFields)
+	}
+	if report.FinishedAt.Before(report.StartedAt) {
+		t.Errorf("FinishedAt before StartedAt")
+	}
+}
+
+func TestMotoParser_StreamAllMoto_FirstPageCheck(t *testing.T) {
+	srv := newCatalogServer(t, "malformed_cards")
+	defer srv.Close()
+
+	parser := newTestParser(t, srv.URL+"/catalog/mototsikly/", 10, WithRateInterval(0))
+
+	errDrift := errors.New("drift")
+	var checked domain.ParseReport
+	opts := domain.ParseOptions{
+		FirstPage: func(report domain.ParseReport) error {
+			checked = report
+			return errDrift
+		},
+	}
+
+	out := make(chan domain.Moto, 10)
+	_, err := parser.StreamAllMoto(context.Background(), opts, out)
+	if !errors.Is(err, errDrift) {
+		t.Fatalf("expected first page check error, got %v", err)
+	}
+	// До проверки мотоциклы первой страницы дальше не уходят, но в отчете уже учтены
+	if len(out) != 0 {
+		t.Errorf("%d motos sent before the first page check", len(out))
+	}
+	if checked.CardsFound != 4 || checked.CardsParsed != 2 || checked.EmptyFields["mileage"] != 1 {
+		t.Errorf("unexpected first page report: found %d, parsed %d, empty %v", checked.CardsFound, checked.CardsParsed, checked.EmptyFields)
+	}
+}
+
+func TestMotoParser_GetAllMoto_PaginationMode(t *testing.T) {
+	tests := []struct {
+		dir      string
+		wantMode string
+	}{
+		{dir: "pagination", wantMode: domain.PaginationCumulative},
+		{dir: "pagination_paged", wantMode: domain.PaginationPaged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			srv := newCatalogServer(t, tt.dir)
+			defer srv.Close()
+
+			// maxPageCount заведомо больше числа страниц: обход должен
+			// остановиться сам, когда страница не добавит новых объявлений
+			parser := newTestParser(t, srv.URL+"/catalog/mototsikly/", 100, WithRateInterval(0), WithWorkers(1))
+
+			motos, report, err := parser.GetAllMoto(context.Background(), domain.ParseOptions{})
+			if err != nil {
+				t.Fatalf("get all moto error: %v", err)
+			}
+
+			if report.PaginationMode != tt.wantMode {
+				t.Errorf("PaginationMode = %q, want %q", report.PaginationMode, tt.wantMode)
+			}
+			if len(motos) != 4 {
+				t.Errorf("expected 4 unique motos, got %d", len(motos))
+			}
+			if report.PagesFetched != 3 {
+				t.Errorf("PagesFetched = %d, want 3", report.PagesFetched)
+			}
+
+			seen := make(map[string]bool)
+			for _, m := range motos {
+				if seen[m.SourceURL] {
+					t.Errorf("duplicate moto %s", m.SourceURL)
+				}
+				seen[m.SourceURL] = true
+			}
+		})
+	}
+}
+
+func TestMotoParser_GetAllMoto_SnapshotReplay(t *testing.T) {
+	snapshotDir := t.TempDir()
+
+	srv := newCatalogServer(t, "details")
+	parser := newTestParser(
+		t,
+		srv.URL+"/catalog/mototsikly/",
+		10,
+		WithRateInterval(0),
+		WithSnapshotDir(snapshotDir),
+	)
+
+	opts := domain.ParseOptions{WithDetails: true}
+	liveMotos, liveReport, err := parser.GetAllMoto(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("get all moto error: %v", err)
+	}
+	srv.Close()
+
+	if liveReport.Snapshot == "" {
+		t.Fatal("snapshot id is not reported")
+	}
+	files, _ := filepath.Glob(filepath.Join(snapshotDir, liveReport.Snapshot, "*.html.gz"))
+	if want := liveReport.PagesFetched + liveReport.DetailPagesFetched; len(files) != want {
+		t.Errorf("snapshot has %d pages, want %d", len(files), want)
+	}
+
+	// Сервер уже остановлен: все страницы должны прийти из снимка
+	opts.Snapshot = liveReport.Snapshot
+	replayMotos, replayReport, err := parser.GetAllMoto(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("replay snapshot error: %v", err)
+	}
+
+	if replayReport.Snapshot != liveReport.Snapshot {
+		t.Errorf("replay snapshot = %q, want %q", replayReport.Snapshot, liveReport.Snapshot)
+	}
+	if replayReport.DetailPagesFailed != liveReport.DetailPagesFailed {
+		t.Errorf("detail pages failed on replay: %d, live: %d", replayReport.DetailPagesFailed, liveReport.DetailPagesFailed)
+	}
+
+	for _, motos := range [][]domain.Moto{liveMotos, replayMotos} {
+		for i := range motos {
+			motos[i].DetailsParsedAt = nil
+		}
+	}
+	live, _ := json.Marshal(liveMotos)
+	replay, _ := json.Marshal(replayMotos)
+	if string(live) != string(replay) {
+		t.Errorf("replay differs from live crawl\nlive:   %s\nreplay: %s", live, replay)
+	}
+
+	for _, id := range []string{"unknown", "../" + liveReport.Snapshot} {
+		opts.Snapshot = id
+		if _, _, err := parser.GetAllMoto(context.Background(), opts); !errors.Is(err, domain.SnapshotNotFound) {
+			t.Errorf("snapshot %q: expected SnapshotNotFound, got %v", id, err)
+		}
+	}
+}
+
+func TestLoadMarkup_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:    "empty selector",
+			config:  `{"card": ""}`,
+			wantErr: "card is empty",
+		},
+		{
+			name:    "unknown field",
+			config:  `{"labels": {"colour": ["Цвет"]}}`,
+			wantErr: "unknown field \"colour\"",
+		},
+		{
+			name:    "missing labels",
+			config:  `{"labels": {"year": ["Год"]}}`,
+			wantErr: "no labels for field \"mileage\"",
+		},
+		{
+			name:    "label used twice",
+			config:  `{"labels": {"year": ["Год"], "mileage": ["год:"]}}`,
+			wantErr: "used for both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "markup.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatalf("write config error: %v", err)
+			}
+
+			_, err := LoadMarkup(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
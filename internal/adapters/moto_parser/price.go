@@ -0,0 +1,217 @@
+package motoparser
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+
+	"golang.org/x/net/html"
+)
+
+// Разобранная цена карточки
+type price struct {
+	current int64
+	old int64
+	currency string
+	status string
+}
+
+// Фразы, которыми сайты заменяют цену
+var onRequestPhrases = []string{"по запросу", "договорн", "по договоренности", "уточняйте", "звоните"}
+
+/*
+parseCardPrice разбирает блок цены карточки.
+Старая (зачеркнутая) цена берется из элементов <s>/<del>/<strike> внутри блока
+или из селектора old_price, если он задан в разметке. Если в блоке просто
+две суммы подряд ("1 390 000 р. 1 190 000 р."), большая первая считается старой.
+*/
+func parseCardPrice(card *html.Node, markup Markup) price {
+	p := price{status: domain.PriceStatusUnknown}
+
+	priceNode := markup.price.MatchFirst(card)
+	if priceNode == nil {
+		return p
+	}
+
+	isOld := func(n *html.Node) bool {
+		return isStrikeNode(n) || (markup.OldPrice != "" && markup.oldPrice.Match(n))
+	}
+
+	currentText, oldText := splitPriceText(priceNode, isOld)
+	if oldText == "" && markup.OldPrice != "" {
+		oldText = getText(markup.oldPrice.MatchFirst(card))
+	}
+
+	return parsePriceText(currentText, oldText)
+}
+
+// parsePriceText разбирает уже извлеченный текст текущей и старой цены.
+func parsePriceText(currentText, oldText string) price {
+	p := price{status: domain.PriceStatusUnknown}
+
+	p.currency = detectCurrency(currentText + " " + oldText)
+	lower := strings.ToLower(currentText)
+
+	for _, phrase := range onRequestPhrases {
+		if strings.Contains(lower, phrase) {
+			p.status = domain.PriceStatusOnRequest
+			return p
+		}
+	}
+
+	amounts := parseAmounts(currentText)
+	if len(amounts) == 0 {
+		return p
+	}
+
+	p.current = amounts[0]
+	if len(amounts) > 1 && amounts[0] > amounts[len(amounts)-1] {
+		p.old, p.current = amounts[0], amounts[len(amounts)-1]
+	}
+
+	if oldAmounts := parseAmounts(oldText); len(oldAmounts) > 0 && oldAmounts[0] > p.current {
+		p.old = oldAmounts[0]
+	}
+
+	p.status = domain.PriceStatusFixed
+	if fields := strings.Fields(lower); len(fields) > 0 && (fields[0] == "от" || fields[0] == "from") {
+		p.status = domain.PriceStatusFrom
+	}
+
+	if p.currency == "" {
+		p.currency = domain.PriceCurrencyRUB // сайты в каталоге российские
+	}
+
+	return p
+}
+
+func isStrikeNode(n *html.Node) bool {
+	return n.Type == html.ElementNode && (n.Data == "s" || n.Data == "del" || n.Data == "strike")
+}
+
+// splitPriceText собирает текст блока цены отдельно для текущей и старой цены.
+func splitPriceText(n *html.Node, isOld func(*html.Node) bool) (string, string) {
+	var current, old strings.Builder
+
+	var walk func(node *html.Node, inOld bool)
+	walk = func(node *html.Node, inOld bool) {
+		if node.Type == html.ElementNode && isOld(node) {
+			inOld = true
+		}
+		if node.Type == html.TextNode {
+			if inOld {
+				old.WriteString(node.Data)
+				old.WriteByte(' ')
+			} else {
+				current.WriteString(node.Data)
+				current.WriteByte(' ')
+			}
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, inOld)
+		}
+	}
+
+	walk(n, false)
+	return strings.TrimSpace(current.String()), strings.TrimSpace(old.String())
+}
+
+/*
+parseAmounts достает суммы из строки: "1 190 000 р." -> [1190000],
+"1 390 000 ₽ 1 190 000 ₽" -> [1390000 1190000]. Копейки отбрасываются.
+*/
+func parseAmounts(s string) []int64 {
+	var amounts []int64
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		if !isASCIIDigit(runes[i]) {
+			i++
+			continue
+		}
+
+		var amount int64
+		amount, _, i = scanNumber(runes, i)
+		amounts = append(amounts, amount)
+	}
+
+	return amounts
+}
+
+/*
+scanNumber читает число, которое начинается с runes[from], и возвращает
+целую часть, дробную и индекс за числом. Пробелы (в т.ч. неразрывные), точки
+и запятые перед группой из трех цифр - разделители разрядов: "12.500" и
+"1 234 567" - целые. Точка или запятая перед другим числом цифр - десятичная:
+"73,4", "12,5". Общий разбор для цен и характеристик (parseLeadingNumber).
+*/
+func scanNumber(runes []rune, from int) (int64, float64, int) {
+	var whole int64
+	var frac float64
+
+	i := from
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case isASCIIDigit(r):
+			whole = whole*10 + int64(r-'0')
+			i++
+		case isDigitSpace(r) && digitsAhead(runes, i+1) == 3:
+			i++
+		case (r == '.' || r == ',') && digitsAhead(runes, i+1) == 3:
+			i++
+		case (r == '.' || r == ',') && digitsAhead(runes, i+1) > 0:
+			scale := 0.1
+			for _, d := range runes[i+1 : i+1+digitsAhead(runes, i+1)] {
+				frac += float64(d-'0') * scale
+				scale /= 10
+			}
+			return whole, frac, i + 1 + digitsAhead(runes, i+1)
+		default:
+			return whole, frac, i
+		}
+	}
+
+	return whole, frac, i
+}
+
+func isASCIIDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isDigitSpace(r rune) bool {
+	return r == ' ' || r == '\u00a0' || r == '\u202f' || r == '\u2009'
+}
+
+func digitsAhead(runes []rune, from int) int {
+	n := 0
+	for from+n < len(runes) && isASCIIDigit(runes[from+n]) {
+		n++
+	}
+	return n
+}
+
+// detectCurrency возвращает ISO-код валюты по символу или сокращению в тексте.
+func detectCurrency(s string) string {
+	lower := strings.ToLower(s)
+
+	switch {
+	case strings.Contains(lower, "₽") || strings.Contains(lower, "руб") || strings.Contains(lower, "rub"):
+		return domain.PriceCurrencyRUB
+	case strings.Contains(lower, "$") || strings.Contains(lower, "usd"):
+		return "USD"
+	case strings.Contains(lower, "€") || strings.Contains(lower, "eur"):
+		return "EUR"
+	}
+
+	for _, field := range strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		if field == "р" {
+			return domain.PriceCurrencyRUB
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,86 @@
+package motoparser
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+func TestParseAmounts(t *testing.T) {
+	tests := []struct {
+		text string
+		want []int64
+	}{
+		{"1 190 000 р.", []int64{1190000}},
+		{"1 190 000 ₽", []int64{1190000}},
+		{"1.190.000 руб.", []int64{1190000}},
+		{"1 190 000,50 ₽", []int64{1190000}},
+		{"от 990 000 ₽", []int64{990000}},
+		{"1 390 000 ₽ 1 190 000 ₽", []int64{1390000, 1190000}},
+		{"990 000 - 1 090 000 ₽", []int64{990000, 1090000}},
+		{"$12,500", []int64{12500}},
+		{"Цена по запросу", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := parseAmounts(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("parseAmounts(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectCurrency(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"1 190 000 ₽", domain.PriceCurrencyRUB},
+		{"1 190 000 руб.", domain.PriceCurrencyRUB},
+		{"1 190 000 р.", domain.PriceCurrencyRUB},
+		{"1 190 000 RUB", domain.PriceCurrencyRUB},
+		{"$12 500", "USD"},
+		{"12 500 USD", "USD"},
+		{"12 500 €", "EUR"},
+		{"12 500 eur", "EUR"},
+		{"Цена по запросу", ""},
+		{"1 190 000", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := detectCurrency(tt.text); got != tt.want {
+				t.Errorf("detectCurrency(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePriceText(t *testing.T) {
+	tests := []struct {
+		current, old string
+		want price
+	}{
+		{"1 190 000 р.", "", price{current: 1190000, currency: domain.PriceCurrencyRUB, status: domain.PriceStatusFixed}},
+		{"от 990 000 ₽", "", price{current: 990000, currency: domain.PriceCurrencyRUB, status: domain.PriceStatusFrom}},
+		{"990 000 - 1 090 000 ₽", "", price{current: 990000, currency: domain.PriceCurrencyRUB, status: domain.PriceStatusFixed}},
+		{"1 390 000 р. 1 190 000 р.", "", price{current: 1190000, old: 1390000, currency: domain.PriceCurrencyRUB, status: domain.PriceStatusFixed}},
+		{"1 190 000 ₽", "1 390 000 ₽", price{current: 1190000, old: 1390000, currency: domain.PriceCurrencyRUB, status: domain.PriceStatusFixed}},
+		{"$12 500", "", price{current: 12500, currency: "USD", status: domain.PriceStatusFixed}},
+		{"1 190 000", "", price{current: 1190000, currency: domain.PriceCurrencyRUB, status: domain.PriceStatusFixed}},
+		{"Цена по запросу", "", price{status: domain.PriceStatusOnRequest}},
+		{"Договорная", "", price{status: domain.PriceStatusOnRequest}},
+		{"", "", price{status: domain.PriceStatusUnknown}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.current, func(t *testing.T) {
+			if got := parsePriceText(tt.current, tt.old); got != tt.want {
+				t.Errorf("parsePriceText(%q, %q) = %+v, want %+v", tt.current, tt.old, got, tt.want)
+			}
+		})
+	}
+}
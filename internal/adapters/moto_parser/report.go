@@ -0,0 +1,219 @@
+package motoparser
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+/*
+reportBuilder собирает domain.ParseReport во время обхода.
+Страницы и объявления разбираются параллельно, поэтому все под мьютексом.
+Методы можно вызывать у nil - тогда диагностика просто не пишется.
+*/
+type reportBuilder struct {
+	mu sync.Mutex
+	report domain.ParseReport
+	progress func(domain.ParseProgress) // ход обхода наружу, см. domain.ParseOptions.Progress
+}
+
+func newReportBuilder() *reportBuilder {
+	return &reportBuilder{
+		report: domain.ParseReport{
+			StartedAt: time.Now(),
+			UnknownLabels: make(map[string]int),
+			UnknownMotoTypes: make(map[string]int),
+			EmptyFields: make(map[string]int),
+		},
+	}
+}
+
+func (b *reportBuilder) pageFetched() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.report.PagesFetched++
+	b.mu.Unlock()
+
+	if b.progress != nil {
+		b.progress(domain.ParseProgress{Pages: 1})
+	}
+}
+
+func (b *reportBuilder) cardsFound(n int) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.CardsFound += n
+}
+
+func (b *reportBuilder) snapshot(id string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.Snapshot = id
+}
+
+func (b *reportBuilder) truncated() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.Truncated = true
+}
+
+func (b *reportBuilder) paginationMode(mode string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.PaginationMode = mode
+}
+
+func (b *reportBuilder) cardRejected(pageURL string, index int, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.CardsRejected++
+	if len(b.report.RejectedCards) < domain.MaxRejectedCardsInReport {
+		b.report.RejectedCards = append(b.report.RejectedCards, domain.RejectedCard{
+			PageURL: pageURL,
+			Index: index,
+			Reason: err.Error(),
+		})
+	}
+}
+
+func (b *reportBuilder) labelSeen() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.LabelsSeen++
+}
+
+func (b *reportBuilder) unknownLabel(label string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.UnknownLabels[normalizeLabel(label)]++
+}
+
+func (b *reportBuilder) unknownMotoType(motoType string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.UnknownMotoTypes[strings.TrimSpace(motoType)]++
+}
+
+func (b *reportBuilder) detailPage(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil {
+		b.report.DetailPagesFailed++
+		return
+	}
+	b.report.DetailPagesFetched++
+}
+
+// motoParsed учитывает готовый мотоцикл и его пустые поля.
+func (b *reportBuilder) motoParsed(m domain.Moto) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.CardsParsed++
+	for _, field := range emptyFields(m) {
+		b.report.EmptyFields[field]++
+	}
+}
+
+// preview - отчет на текущий момент так, как если бы motos уже были отправлены.
+func (b *reportBuilder) preview(motos []domain.Moto) domain.ParseReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	report := b.report
+	report.RejectedCards = slices.Clone(b.report.RejectedCards)
+	report.UnknownLabels = maps.Clone(b.report.UnknownLabels)
+	report.UnknownMotoTypes = maps.Clone(b.report.UnknownMotoTypes)
+	report.EmptyFields = maps.Clone(b.report.EmptyFields)
+
+	report.CardsParsed += len(motos)
+	for _, m := range motos {
+		for _, field := range emptyFields(m) {
+			report.EmptyFields[field]++
+		}
+	}
+
+	return report
+}
+
+// finish закрывает отчет.
+func (b *reportBuilder) finish(err error) domain.ParseReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.report.FinishedAt = time.Now()
+	if err != nil {
+		b.report.Error = err.Error()
+	}
+
+	return b.report
+}
+
+func emptyFields(m domain.Moto) []string {
+	var fields []string
+
+	check := func(field string, empty bool) {
+		if empty {
+			fields = append(fields, field)
+		}
+	}
+
+	check("name", m.Name == "")
+	check("brand", m.Brand == "")
+	check(fieldYear, m.Year == 0)
+	check(fieldMileage, m.MileageOriginal == 0)
+	check(fieldEngineSize, m.EngineSize == 0 && m.MotorPowerKW == 0)
+	check(fieldMotoType, m.MotoType == "")
+	check("class", m.Class == "")
+	check(fieldLocation, m.Location == "")
+	check("price", m.PriceStatus == domain.PriceStatusUnknown)
+	check("source_url", m.SourceURL == "")
+	check("image_url", m.ImageURL == "")
+
+	return fields
+}
@@ -0,0 +1,499 @@
+package motoparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+/*
+Selector - скомпилированный CSS-селектор для дерева *html.Node.
+
+Поддерживается:
+  - тег и *: div, *
+  - классы и id: .slider-card__title, #catalog
+  - атрибуты: [href], [data-src=x], [class~=x], [href^=x], [href$=x], [href*=x]
+  - псевдоклассы: :nth-child(2), :nth-child(2n+1), :nth-child(odd), :first-child, :last-child
+  - комбинаторы: потомок (пробел) и прямой ребенок (>)
+  - списки селекторов через запятую
+*/
+type Selector struct {
+	source string
+	groups []complexSelector
+}
+
+// complexSelector хранит составные селекторы слева направо,
+// combinators[i] связывает compounds[i] и compounds[i+1].
+type complexSelector struct {
+	compounds []compoundSelector
+	combinators []byte
+}
+
+type compoundSelector struct {
+	tag string // пусто или * - любой тег
+	matchers []func(n *html.Node) bool
+}
+
+func CompileSelector(s string) (Selector, error) {
+	p := &selectorParser{src: []rune(strings.TrimSpace(s))}
+
+	groups, err := p.parseList()
+	if err != nil {
+		return Selector{}, fmt.Errorf("invalid selector %q: %w", s, err)
+	}
+
+	return Selector{source: s, groups: groups}, nil
+}
+
+func (s Selector) String() string {
+	return s.source
+}
+
+// Match проверяет, подходит ли узел под селектор.
+func (s Selector) Match(n *html.Node) bool {
+	if n == nil || n.Type != html.ElementNode {
+		return false
+	}
+
+	for _, group := range s.groups {
+		if group.match(n, len(group.compounds)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAll возвращает всех потомков root, подходящих под селектор, в порядке документа.
+// Сам root не проверяется, как в querySelectorAll.
+func (s Selector) MatchAll(root *html.Node) []*html.Node {
+	var result []*html.Node
+	if root == nil {
+		return result
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if s.Match(child) {
+				result = append(result, child)
+			}
+			walk(child)
+		}
+	}
+
+	walk(root)
+	return result
+}
+
+// MatchFirst возвращает первый подходящий узел в порядке документа или nil.
+// В отличие от MatchAll, сам root тоже проверяется: ссылкой может оказаться
+// сам заголовок карточки, а не его потомок.
+func (s Selector) MatchFirst(root *html.Node) *html.Node {
+	if root == nil {
+		return nil
+	}
+	if s.Match(root) {
+		return root
+	}
+
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if n := s.MatchFirst(child); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// match проверяет compounds[0..i] справа налево, начиная с узла n.
+func (c complexSelector) match(n *html.Node, i int) bool {
+	if !c.compounds[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	switch c.combinators[i-1] {
+	case '>':
+		parent := n.Parent
+		return parent != nil && parent.Type == html.ElementNode && c.match(parent, i-1)
+	default:
+		for parent := n.Parent; parent != nil && parent.Type == html.ElementNode; parent = parent.Parent {
+			if c.match(parent, i-1) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (c compoundSelector) match(n *html.Node) bool {
+	if c.tag != "" && c.tag != "*" && n.Data != c.tag {
+		return false
+	}
+
+	for _, m := range c.matchers {
+		if !m(n) {
+			return false
+		}
+	}
+	return true
+}
+
+type selectorParser struct {
+	src []rune
+	pos int
+}
+
+func (p *selectorParser) parseList() ([]complexSelector, error) {
+	var groups []complexSelector
+
+	for {
+		group, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+
+		p.skipSpaces()
+		if p.eof() {
+			return groups, nil
+		}
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("unexpected %q at %d", p.peek(), p.pos)
+		}
+		p.pos++
+		p.skipSpaces()
+	}
+}
+
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	var c complexSelector
+
+	compound, err := p.parseCompound()
+	if err != nil {
+		return c, err
+	}
+	c.compounds = append(c.compounds, compound)
+
+	for {
+		hadSpace := p.skipSpaces()
+		if p.eof() || p.peek() == ',' {
+			return c, nil
+		}
+
+		combinator := byte(' ')
+		if p.peek() == '>' {
+			combinator = '>'
+			p.pos++
+			p.skipSpaces()
+		} else if !hadSpace {
+			return c, fmt.Errorf("unexpected %q at %d", p.peek(), p.pos)
+		}
+
+		compound, err := p.parseCompound()
+		if err != nil {
+			return c, err
+		}
+		c.combinators = append(c.combinators, combinator)
+		c.compounds = append(c.compounds, compound)
+	}
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var c compoundSelector
+	start := p.pos
+
+	if !p.eof() && p.peek() == '*' {
+		c.tag = "*"
+		p.pos++
+	} else if !p.eof() && isIdentRune(p.peek()) {
+		c.tag = strings.ToLower(p.parseIdent())
+	}
+
+	for !p.eof() {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			class := p.parseIdent()
+			if class == "" {
+				return c, fmt.Errorf("empty class name at %d", p.pos)
+			}
+			c.matchers = append(c.matchers, func(n *html.Node) bool {
+				return hasClass(n, class)
+			})
+		case '#':
+			p.pos++
+			id := p.parseIdent()
+			if id == "" {
+				return c, fmt.Errorf("empty id at %d", p.pos)
+			}
+			c.matchers = append(c.matchers, func(n *html.Node) bool {
+				return getAttr(n, "id") == id
+			})
+		case '[':
+			m, err := p.parseAttr()
+			if err != nil {
+				return c, err
+			}
+			c.matchers = append(c.matchers, m)
+		case ':':
+			m, err := p.parsePseudo()
+			if err != nil {
+				return c, err
+			}
+			c.matchers = append(c.matchers, m)
+		default:
+			if p.pos == start {
+				return c, fmt.Errorf("expected selector at %d", p.pos)
+			}
+			return c, nil
+		}
+	}
+
+	if p.pos == start {
+		return c, fmt.Errorf("expected selector at %d", p.pos)
+	}
+	return c, nil
+}
+
+func (p *selectorParser) parseAttr() (func(*html.Node) bool, error) {
+	p.pos++ // [
+	p.skipSpaces()
+
+	key := strings.ToLower(p.parseIdent())
+	if key == "" {
+		return nil, fmt.Errorf("empty attribute name at %d", p.pos)
+	}
+	p.skipSpaces()
+
+	if p.eof() {
+		return nil, fmt.Errorf("unclosed attribute selector")
+	}
+
+	if p.peek() == ']' {
+		p.pos++
+		return func(n *html.Node) bool {
+			return hasAttr(n, key)
+		}, nil
+	}
+
+	op := ""
+	if strings.ContainsRune("~^$*", p.peek()) {
+		op = string(p.peek())
+		p.pos++
+	}
+	if p.eof() || p.peek() != '=' {
+		return nil, fmt.Errorf("expected = in attribute selector at %d", p.pos)
+	}
+	p.pos++
+	p.skipSpaces()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.eof() || p.peek() != ']' {
+		return nil, fmt.Errorf("unclosed attribute selector at %d", p.pos)
+	}
+	p.pos++
+
+	return func(n *html.Node) bool {
+		if !hasAttr(n, key) {
+			return false
+		}
+		v := getAttr(n, key)
+		switch op {
+		case "~":
+			for _, field := range strings.Fields(v) {
+				if field == value {
+					return true
+				}
+			}
+			return false
+		case "^":
+			return value != "" && strings.HasPrefix(v, value)
+		case "$":
+			return value != "" && strings.HasSuffix(v, value)
+		case "*":
+			return value != "" && strings.Contains(v, value)
+		default:
+			return v == value
+		}
+	}, nil
+}
+
+func (p *selectorParser) parsePseudo() (func(*html.Node) bool, error) {
+	p.pos++ // :
+	name := strings.ToLower(p.parseIdent())
+
+	switch name {
+	case "first-child":
+		return nthChild(0, 1), nil
+	case "last-child":
+		return func(n *html.Node) bool {
+			for s := n.NextSibling; s != nil; s = s.NextSibling {
+				if s.Type == html.ElementNode {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case "nth-child":
+		if p.eof() || p.peek() != '(' {
+			return nil, fmt.Errorf(":nth-child requires an argument")
+		}
+		p.pos++
+
+		end := strings.IndexRune(string(p.src[p.pos:]), ')')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed :nth-child(")
+		}
+		arg := string(p.src[p.pos:])[:end]
+		p.pos += len([]rune(arg)) + 1
+
+		a, b, err := parseNth(arg)
+		if err != nil {
+			return nil, err
+		}
+		return nthChild(a, b), nil
+	default:
+		return nil, fmt.Errorf("unsupported pseudo-class :%s", name)
+	}
+}
+
+// nthChild проверяет, что узел стоит на позиции a*k+b (k >= 0) среди братьев-элементов.
+func nthChild(a, b int) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		pos := 1
+		for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+			if s.Type == html.ElementNode {
+				pos++
+			}
+		}
+
+		if a == 0 {
+			return pos == b
+		}
+		diff := pos - b
+		return diff%a == 0 && diff/a >= 0
+	}
+}
+
+// parseNth разбирает аргумент :nth-child: "odd", "even", "3", "2n+1", "-n+3".
+func parseNth(arg string) (a, b int, err error) {
+	arg = strings.ToLower(strings.ReplaceAll(arg, " ", ""))
+
+	switch arg {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	case "":
+		return 0, 0, fmt.Errorf("empty :nth-child argument")
+	}
+
+	nIdx := strings.IndexByte(arg, 'n')
+	if nIdx < 0 {
+		b, err = strconv.Atoi(arg)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid :nth-child argument %q", arg)
+		}
+		return 0, b, nil
+	}
+
+	switch aPart := arg[:nIdx]; aPart {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		a, err = strconv.Atoi(aPart)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid :nth-child argument %q", arg)
+		}
+	}
+
+	if bPart := arg[nIdx+1:]; bPart != "" {
+		b, err = strconv.Atoi(bPart)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid :nth-child argument %q", arg)
+		}
+	}
+
+	return a, b, nil
+}
+
+func (p *selectorParser) parseValue() (string, error) {
+	if p.eof() {
+		return "", fmt.Errorf("expected attribute value")
+	}
+
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		return p.parseIdent(), nil
+	}
+
+	p.pos++
+	start := p.pos
+	for !p.eof() && p.peek() != quote {
+		p.pos++
+	}
+	if p.eof() {
+		return "", fmt.Errorf("unclosed string in attribute selector")
+	}
+
+	value := string(p.src[start:p.pos])
+	p.pos++
+	return value, nil
+}
+
+func (p *selectorParser) parseIdent() string {
+	start := p.pos
+	for !p.eof() && isIdentRune(p.peek()) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *selectorParser) skipSpaces() bool {
+	start := p.pos
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) peek() rune {
+	return p.src[p.pos]
+}
+
+func (p *selectorParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || r > unicode.MaxASCII
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(getAttr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
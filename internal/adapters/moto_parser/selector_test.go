@@ -0,0 +1,110 @@
+package motoparser
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const selectorTestHTML = `
+<div id="catalog" class="page-card">
+  <div class="page-card__col" data-id="1">
+    <div class="slider-card__row"><span>Год</span><span>2019</span></div>
+    <div class="slider-card__row"><span>Пробег ТС</span><span>12 500 км</span></div>
+    <a href="/catalog/mototsikly/yamaha/">Yamaha</a>
+  </div>
+  <div class="page-card__col sold" data-id="2">
+    <div class="slider-card__row"><span>Год</span><span>2021</span></div>
+    <p><a href="https://example.com/honda">Honda</a></p>
+  </div>
+  <div class="page-card__col" data-id="3"></div>
+</div>`
+
+func TestSelector_MatchAll(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorTestHTML))
+	if err != nil {
+		t.Fatalf("parse html error: %v", err)
+	}
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{selector: ".page-card__col", want: []string{"1", "2", "3"}},
+		{selector: "div.page-card__col.sold", want: []string{"2"}},
+		{selector: "#catalog > div:nth-child(2)", want: []string{"2"}},
+		{selector: ".page-card__col:nth-child(odd)", want: []string{"1", "3"}},
+		{selector: ".page-card__col:last-child", want: []string{"3"}},
+		{selector: "[data-id='2'], [data-id=3]", want: []string{"2", "3"}},
+		{selector: "div.slider-card__row:nth-child(2) span:nth-child(2)", want: []string{"12 500 км"}},
+		{selector: ".page-card__col > a", want: []string{"Yamaha"}},
+		{selector: ".page-card__col a[href^=https]", want: []string{"Honda"}},
+		{selector: "a[href$='/yamaha/']", want: []string{"Yamaha"}},
+		{selector: "[class~=sold] a[href*=example]", want: []string{"Honda"}},
+		{selector: ".page-card__col:nth-child(-n+1) .slider-card__row span:first-child", want: []string{"Год", "Пробег ТС"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := CompileSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("compile error: %v", err)
+			}
+
+			var got []string
+			for _, n := range sel.MatchAll(doc) {
+				if id := getAttr(n, "data-id"); id != "" {
+					got = append(got, id)
+				} else {
+					got = append(got, getText(n))
+				}
+			}
+
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelector_MatchFirst(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorTestHTML))
+	if err != nil {
+		t.Fatalf("parse html error: %v", err)
+	}
+
+	cols, err := CompileSelector(".page-card__col")
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	link, err := CompileSelector("a")
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+
+	first := cols.MatchFirst(doc)
+	if id := getAttr(first, "data-id"); id != "1" {
+		t.Fatalf("first column: got %q, want %q", id, "1")
+	}
+	if text := getText(link.MatchFirst(first)); text != "Yamaha" {
+		t.Errorf("first link in column: got %q, want %q", text, "Yamaha")
+	}
+
+	// Подходящий root возвращается сам
+	a := link.MatchFirst(first)
+	if got := link.MatchFirst(a); got != a {
+		t.Errorf("root is not matched: got %v", got)
+	}
+	if got := cols.MatchFirst(a); got != nil {
+		t.Errorf("expected no match below the link, got %v", got)
+	}
+}
+
+func TestCompileSelector_Errors(t *testing.T) {
+	for _, s := range []string{"", ".", "div >", "[href", "a:hover", ":nth-child(x)", "a, ", "a[href~]"} {
+		if _, err := CompileSelector(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
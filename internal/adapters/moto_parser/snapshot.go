@@ -0,0 +1,220 @@
+package motoparser
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+/*
+Архив сырых страниц.
+
+Каждый обход с включенным архивом пишет отдельный снимок:
+
+	<snapshot_dir>/<id>/index.ndjson   - по строке на страницу: url, время, файл
+	<snapshot_dir>/<id>/0001.html.gz   - тело страницы как пришло с сайта
+
+id - время начала обхода в UTC, он же попадает в отчет (ParseReport.Snapshot).
+Снимок потом можно "проиграть" через ParseOptions.Snapshot: парсер возьмет
+страницы из архива вместо сети и разберет их текущей разметкой.
+*/
+
+const (
+	snapshotIndexFile = "index.ndjson"
+	snapshotIDLayout = "20060102T150405.000Z"
+)
+
+// Страницы нет в снимке. Для каталога это значит, что обход в свое время на ней закончился.
+var errPageNotInSnapshot = errors.New("page not in snapshot")
+
+// pageFetcher - откуда берутся страницы: сеть, сеть с записью в архив или архив.
+type pageFetcher interface {
+	fetch(ctx context.Context, pageURL string) ([]byte, error)
+}
+
+type snapshotEntry struct {
+	URL string `json:"url"`
+	FetchedAt time.Time `json:"fetched_at"`
+	File string `json:"file"`
+}
+
+// snapshotRecorder качает страницы через next и складывает копию каждой в снимок.
+type snapshotRecorder struct {
+	next pageFetcher
+	id string
+	dir string
+
+	mu sync.Mutex
+	seq int
+	index *os.File
+}
+
+func newSnapshotRecorder(root string, next pageFetcher) (*snapshotRecorder, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create snapshot dir error: %w", err)
+	}
+
+	// Два обхода в одну миллисекунду не должны писать в один снимок
+	base := time.Now().UTC().Format(snapshotIDLayout)
+	id := base
+	for i := 2; ; i++ {
+		err := os.Mkdir(filepath.Join(root, id), 0o755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("create snapshot error: %w", err)
+		}
+		id = base + "-" + strconv.Itoa(i)
+	}
+
+	dir := filepath.Join(root, id)
+	index, err := os.OpenFile(filepath.Join(dir, snapshotIndexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("create snapshot index error: %w", err)
+	}
+
+	return &snapshotRecorder{next: next, id: id, dir: dir, index: index}, nil
+}
+
+// fetch не падает из-за архива: обход важнее, ошибка записи только логируется.
+func (r *snapshotRecorder) fetch(ctx context.Context, pageURL string) ([]byte, error) {
+	body, err := r.next.fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.save(pageURL, body); err != nil {
+		log.Printf("MotoParser-snapshot: save %s error: %v", pageURL, err)
+	}
+
+	return body, nil
+}
+
+func (r *snapshotRecorder) save(pageURL string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	entry := snapshotEntry{
+		URL: pageURL,
+		FetchedAt: time.Now().UTC(),
+		File: fmt.Sprintf("%04d.html.gz", r.seq),
+	}
+
+	if err := writeGzipFile(filepath.Join(r.dir, entry.File), body); err != nil {
+		return err
+	}
+
+	// Строка в индексе пишется после файла, чтобы индекс не ссылался на недописанное
+	return json.NewEncoder(r.index).Encode(entry)
+}
+
+func (r *snapshotRecorder) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.index.Close(); err != nil {
+		log.Printf("MotoParser-snapshot: close index error: %v", err)
+	}
+}
+
+func writeGzipFile(path string, body []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// snapshotReader отдает страницы из ранее записанного снимка, в сеть не ходит.
+type snapshotReader struct {
+	id string
+	dir string
+	files map[string]string // url -> файл
+}
+
+func openSnapshot(root string, id string) (*snapshotReader, error) {
+	// id приходит снаружи (из запроса), наружу из root выходить нельзя
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+		return nil, fmt.Errorf("%w: invalid snapshot id %q", domain.SnapshotNotFound, id)
+	}
+
+	dir := filepath.Join(root, id)
+	index, err := os.Open(filepath.Join(dir, snapshotIndexFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", domain.SnapshotNotFound, id)
+		}
+		return nil, fmt.Errorf("open snapshot %s error: %w", id, err)
+	}
+	defer index.Close()
+
+	files := make(map[string]string)
+	scanner := bufio.NewScanner(index)
+	for scanner.Scan() {
+		var entry snapshotEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("decode snapshot %s index error: %w", id, err)
+		}
+		files[entry.URL] = entry.File
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read snapshot %s index error: %w", id, err)
+	}
+
+	return &snapshotReader{id: id, dir: dir, files: files}, nil
+}
+
+func (r *snapshotReader) fetch(ctx context.Context, pageURL string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	file, ok := r.files[pageURL]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errPageNotInSnapshot, pageURL)
+	}
+
+	f, err := os.Open(filepath.Join(r.dir, file))
+	if err != nil {
+		return nil, fmt.Errorf("%w: snapshot %s: %v", domain.ParseMotoError, r.id, err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: snapshot %s: %s: %v", domain.ParseMotoError, r.id, file, err)
+	}
+	defer zr.Close()
+
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: snapshot %s: %s: %v", domain.ParseMotoError, r.id, file, err)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,59 @@
+package motoparser
+
+import (
+	"context"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+type streamFunc func(
+	ctx context.Context,
+	opts domain.ParseOptions,
+	out chan<- domain.Moto,
+) (domain.ParseReport, error)
+
+// collectMotos собирает все мотоциклы из потокового парсера в слайс.
+func collectMotos(
+	ctx context.Context,
+	opts domain.ParseOptions,
+	stream streamFunc,
+) ([]domain.Moto, domain.ParseReport, error) {
+	out := make(chan domain.Moto)
+
+	var report domain.ParseReport
+	var err error
+	go func() {
+		defer close(out)
+		report, err = stream(ctx, opts, out)
+	}()
+
+	var motos []domain.Moto
+	for moto := range out {
+		motos = append(motos, moto)
+	}
+
+	if err != nil {
+		return nil, report, err
+	}
+	return motos, report, nil
+}
+
+// emitMotos отправляет мотоциклы в out, учитывая их в отчете.
+func emitMotos(
+	ctx context.Context,
+	motos []domain.Moto,
+	rep *reportBuilder,
+	out chan<- domain.Moto,
+) error {
+	for _, moto := range motos {
+		rep.motoParsed(moto)
+
+		select {
+		case out <- moto:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package motoparser
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+const kmPerMile = 1.609344
+
+// Объем без единиц меньше этого числа считаем записанным в литрах: "1.2" - это 1200 cc
+const maxLitreDisplacement = 20
+
+type mileage struct {
+	km int
+	original int
+	unit string
+}
+
+/*
+parseMileage разбирает пробег с единицами: "12 500 км", "12,5 тыс. км",
+"8 000 миль", "350 м/ч". Мили переводятся в км. Моточасы в км не переводятся:
+km остается 0, а часы лежат в original с единицей domain.MileageUnitHours.
+Без единиц считаем, что пробег в км.
+*/
+func parseMileage(value string) (mileage, bool) {
+	v, ok := parseLeadingNumber(value)
+	if !ok {
+		return mileage{}, false
+	}
+
+	words := unitWords(value)
+	if hasWord(words, "тыс", "k") {
+		v *= 1000
+	}
+
+	switch {
+	case hasWordPrefix(words, "моточас", "час", "hour") || hasWord(words, "ч", "мч", "м/ч", "h", "mh", "hrs"):
+		return mileage{original: round(v), unit: domain.MileageUnitHours}, true
+	case hasWordPrefix(words, "мил", "mile") || hasWord(words, "mi"):
+		return mileage{km: round(v * kmPerMile), original: round(v), unit: domain.MileageUnitMiles}, true
+	default:
+		return mileage{km: round(v), original: round(v), unit: domain.MileageUnitKm}, true
+	}
+}
+
+type engine struct {
+	cc int
+	unit string
+	powertrain string
+	powerKW float64
+}
+
+/*
+parseEngine разбирает строку объема двигателя: "821 куб.см", "1.2 л", "998 cc".
+У электромотоциклов в этой строке мощность мотора: "11 кВт", "5000 Вт" -
+тогда объем 0, а мощность пишется в кВт.
+*/
+func parseEngine(value string) (engine, bool) {
+	v, ok := parseLeadingNumber(value)
+	if !ok {
+		return engine{}, false
+	}
+
+	words := unitWords(value)
+	switch {
+	case hasWord(words, "квт", "kw"):
+		return engine{unit: domain.EngineSizeUnitKW, powertrain: domain.PowertrainElectric, powerKW: v}, true
+	case hasWord(words, "вт", "w"):
+		return engine{unit: domain.EngineSizeUnitKW, powertrain: domain.PowertrainElectric, powerKW: v / 1000}, true
+	case hasWord(words, "л", "l", "литр", "литра", "литров"):
+		if v >= maxLitreDisplacement {
+			// "1.250 л": три цифры после точки прочитались как разряды
+			v /= 1000
+		}
+		return engine{cc: round(v * 1000), unit: domain.EngineSizeUnitLitre, powertrain: domain.PowertrainICE}, true
+	case len(words) == 0 && v < maxLitreDisplacement:
+		return engine{cc: round(v * 1000), unit: domain.EngineSizeUnitLitre, powertrain: domain.PowertrainICE}, true
+	default:
+		return engine{cc: round(v), unit: domain.EngineSizeUnitCC, powertrain: domain.PowertrainICE}, true
+	}
+}
+
+// isElectric ищет признаки электромотоцикла в названии или классе.
+func isElectric(m domain.Moto) bool {
+	text := strings.ToLower(m.Name + " " + m.MotoType)
+	return strings.Contains(text, "электро") || strings.Contains(text, "electric")
+}
+
+// unitWords возвращает слова строки без чисел: "12,5 тыс. км" -> [тыс км].
+// "м/ч" и "куб.см" остаются одним словом.
+func unitWords(value string) []string {
+	var words []string
+	for _, field := range strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsDigit(r) || r == ','
+	}) {
+		if field = strings.Trim(field, ".:;()"); field != "" {
+			words = append(words, field)
+		}
+	}
+	return words
+}
+
+func hasWord(words []string, variants ...string) bool {
+	for _, w := range words {
+		for _, v := range variants {
+			if w == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasWordPrefix(words []string, prefixes ...string) bool {
+	for _, w := range words {
+		for _, p := range prefixes {
+			if strings.HasPrefix(w, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func round(v float64) int {
+	return int(math.Round(v))
+}
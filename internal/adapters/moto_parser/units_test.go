@@ -0,0 +1,101 @@
+package motoparser
+
+import (
+	"testing"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+func TestParseLeadingNumber(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+		ok bool
+	}{
+		{"73,4 л.с.", 73.4, true},
+		{"1 200 кг", 1200, true},
+		{"1 200 кг", 1200, true},
+		{"12.500 км", 12500, true},
+		{"1.234.567", 1234567, true},
+		{"1,234,567", 1234567, true},
+		{"12,5 тыс. км", 12.5, true},
+		{"вес: 198 кг", 198, true},
+		{"нет данных", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := parseLeadingNumber(tt.text)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseLeadingNumber(%q) = %v, %v, want %v, %v", tt.text, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseMileage(t *testing.T) {
+	tests := []struct {
+		text string
+		want mileage
+	}{
+		{"12 500 км", mileage{km: 12500, original: 12500, unit: domain.MileageUnitKm}},
+		{"12.500 км", mileage{km: 12500, original: 12500, unit: domain.MileageUnitKm}},
+		{"1.234.567 км", mileage{km: 1234567, original: 1234567, unit: domain.MileageUnitKm}},
+		{"12,5 тыс. км", mileage{km: 12500, original: 12500, unit: domain.MileageUnitKm}},
+		{"12k km", mileage{km: 12000, original: 12000, unit: domain.MileageUnitKm}},
+		{"12500", mileage{km: 12500, original: 12500, unit: domain.MileageUnitKm}},
+		{"8 000 миль", mileage{km: 12875, original: 8000, unit: domain.MileageUnitMiles}},
+		{"5 mi", mileage{km: 8, original: 5, unit: domain.MileageUnitMiles}},
+		{"350 м/ч", mileage{original: 350, unit: domain.MileageUnitHours}},
+		{"1 200 моточасов", mileage{original: 1200, unit: domain.MileageUnitHours}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := parseMileage(tt.text)
+			if !ok || got != tt.want {
+				t.Errorf("parseMileage(%q) = %+v, %v, want %+v", tt.text, got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := parseMileage("не указан"); ok {
+		t.Errorf("parseMileage without a number is ok")
+	}
+}
+
+func TestParseEngine(t *testing.T) {
+	ice := func(cc int, unit string) engine {
+		return engine{cc: cc, unit: unit, powertrain: domain.PowertrainICE}
+	}
+	electric := func(kw float64) engine {
+		return engine{unit: domain.EngineSizeUnitKW, powertrain: domain.PowertrainElectric, powerKW: kw}
+	}
+
+	tests := []struct {
+		text string
+		want engine
+	}{
+		{"821 куб.см", ice(821, domain.EngineSizeUnitCC)},
+		{"998 cc", ice(998, domain.EngineSizeUnitCC)},
+		{"1 301 см³", ice(1301, domain.EngineSizeUnitCC)},
+		{"650", ice(650, domain.EngineSizeUnitCC)},
+		{"1.2 л", ice(1200, domain.EngineSizeUnitLitre)},
+		{"1,250 л", ice(1250, domain.EngineSizeUnitLitre)},
+		{"1.2", ice(1200, domain.EngineSizeUnitLitre)},
+		{"11 кВт", electric(11)},
+		{"11,5 kW", electric(11.5)},
+		{"5000 Вт", electric(5)},
+		{"5 000 Вт", electric(5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := parseEngine(tt.text)
+			if !ok || got != tt.want {
+				t.Errorf("parseEngine(%q) = %+v, %v, want %+v", tt.text, got, ok, tt.want)
+			}
+		})
+	}
+}
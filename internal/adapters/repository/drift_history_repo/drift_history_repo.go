@@ -0,0 +1,70 @@
+package drifthistoryrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+type driftHistoryRepo struct {
+	db *gorm.DB
+	log usecase.Logger
+}
+
+func NewDriftHistoryRepo(db *gorm.DB, log usecase.Logger) usecase.DriftHistoryRepo {
+	return &driftHistoryRepo{
+		db: db,
+		log: log,
+	}
+}
+
+func (r *driftHistoryRepo) Append(ctx context.Context, report domain.ParseReport) error {
+	r.log.Debug("DriftHistoryRepo_Append: Start!", "source", report.Source)
+
+	gormReport, err := toGormDriftReport(report)
+	if err != nil {
+		r.log.Error("DriftHistoryRepo_Append: marshal report error", "source", report.Source, "err", err)
+		return fmt.Errorf("%w: marshal report error: %v", domain.InternalError, err)
+	}
+
+	if err := r.db.WithContext(ctx).Create(&gormReport).Error; err != nil {
+		r.log.Error("DriftHistoryRepo_Append: internal error", "source", report.Source, "err", err)
+		return fmt.Errorf("%w: append drift history error: %v", domain.InternalError, err)
+	}
+
+	r.log.Debug("DriftHistoryRepo_Append: End!", "id", gormReport.ID)
+	return nil
+}
+
+func (r *driftHistoryRepo) Recent(ctx context.Context, source string, limit int) ([]domain.ParseReport, error) {
+	r.log.Debug("DriftHistoryRepo_Recent: Start!", "source", source, "limit", limit)
+
+	query := r.db.WithContext(ctx).Where("source = ?", source).Order("finished_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var gormReports []GormDriftReport
+	if err := query.Find(&gormReports).Error; err != nil {
+		r.log.Error("DriftHistoryRepo_Recent: internal error", "source", source, "err", err)
+		return nil, fmt.Errorf("%w: read drift history error: %v", domain.InternalError, err)
+	}
+
+	reports := make([]domain.ParseReport, 0, len(gormReports))
+	for _, gormReport := range gormReports {
+		report, err := toDomainParseReport(gormReport)
+		if err != nil {
+			// Битая запись не должна ломать сравнение с остальными
+			r.log.Error("DriftHistoryRepo_Recent: unmarshal report error", "id", gormReport.ID, "err", err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	r.log.Debug("DriftHistoryRepo_Recent: End!", "count", len(reports))
+	return reports, nil
+}
@@ -0,0 +1,64 @@
+package drifthistoryrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/repotest"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+var (
+	db *gorm.DB
+	integration = repotest.Integration
+	dhRepo usecase.DriftHistoryRepo
+	lg usecase.Logger
+)
+
+func TestMain(m *testing.M) {
+	repotest.Main(m, func(testDB *gorm.DB) {
+		db = testDB
+		lg = logger.NewLogger()
+		dhRepo = NewDriftHistoryRepo(db, lg)
+	})
+}
+
+func TestDriftHistoryRepo_Recent(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+	source := "test-drift-history"
+	defer db.Where("source = ?", source).Delete(&GormDriftReport{})
+
+	start := time.Now().Add(-time.Hour)
+	for i := 1; i <= 3; i++ {
+		err := dhRepo.Append(ctx, domain.ParseReport{
+			Source: source,
+			FinishedAt: start.Add(time.Duration(i) * time.Minute),
+			CardsFound: i * 10,
+			EmptyFields: map[string]int{"price": i},
+			RejectedCards: []domain.RejectedCard{{Reason: "no title"}},
+		})
+		if err != nil {
+			t.Fatalf("append report error: %v", err)
+		}
+	}
+
+	reports, err := dhRepo.Recent(ctx, source, 2)
+	if err != nil {
+		t.Fatalf("recent reports error: %v", err)
+	}
+	if len(reports) != 2 || reports[0].CardsFound != 30 || reports[1].CardsFound != 20 {
+		t.Fatalf("unexpected recent reports: %+v", reports)
+	}
+	if reports[0].EmptyFields["price"] != 3 || reports[0].RejectedCards != nil {
+		t.Errorf("report is not restored as saved: %+v", reports[0])
+	}
+}
@@ -0,0 +1,31 @@
+package drifthistoryrepo
+
+import (
+	"encoding/json"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+func toDomainParseReport(report GormDriftReport) (domain.ParseReport, error) {
+	var result domain.ParseReport
+	if err := json.Unmarshal([]byte(report.Report), &result); err != nil {
+		return domain.ParseReport{}, err
+	}
+	return result, nil
+}
+
+func toGormDriftReport(report domain.ParseReport) (GormDriftReport, error) {
+	// Отбракованные карточки поименно для сравнения не нужны
+	report.RejectedCards = nil
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return GormDriftReport{}, err
+	}
+
+	return GormDriftReport{
+		Source: report.Source,
+		FinishedAt: report.FinishedAt,
+		Report: string(data),
+	}, nil
+}
@@ -0,0 +1,16 @@
+package drifthistoryrepo
+
+import (
+	"time"
+)
+
+type GormDriftReport struct {
+	ID uint `gorm:"primaryKey;autoIncrement"`
+	Source string `gorm:"type:varchar(100);index:idx_drift_history_source_finished_at,priority:1"`
+	FinishedAt time.Time `gorm:"not null;index:idx_drift_history_source_finished_at,priority:2"`
+	Report string `gorm:"type:text"` // domain.ParseReport в json
+}
+
+func (GormDriftReport) TableName() string {
+	return "drift_history"
+}
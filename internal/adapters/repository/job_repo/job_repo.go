@@ -0,0 +1,124 @@
+package jobrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+type jobRepo struct {
+	db *gorm.DB
+	log usecase.Logger
+}
+
+func NewJobRepo(db *gorm.DB, log usecase.Logger) usecase.JobRepo {
+	return &jobRepo{
+		db: db,
+		log: log,
+	}
+}
+
+func (r *jobRepo) Create(ctx context.Context, job domain.ParseJob) error {
+	r.log.Debug("JobRepo_Create: Start!", "id", job.ID)
+
+	gormJob := toGormParseJob(job)
+	if err := r.db.WithContext(ctx).Create(&gormJob).Error; err != nil {
+		r.log.Error("JobRepo_Create: internal error", "id", job.ID, "err", err)
+		return fmt.Errorf("%w: create job error: %v", domain.InternalError, err)
+	}
+
+	r.log.Debug("JobRepo_Create: End!", "id", job.ID)
+	return nil
+}
+
+// Update перезаписывает состояние задачи целиком, включая нулевые счетчики.
+// Просьбу об отмене не трогает: ее ставит другой экземпляр через RequestCancel.
+func (r *jobRepo) Update(ctx context.Context, job domain.ParseJob) error {
+	r.log.Debug("JobRepo_Update: Start!", "id", job.ID, "status", job.Status)
+
+	gormJob := toGormParseJob(job)
+	result := r.db.WithContext(ctx).Model(&GormParseJob{}).Where("id = ?", job.ID).Select("*").Omit("id", "created_at", "cancel_requested").Updates(&gormJob)
+	if result.Error != nil {
+		r.log.Error("JobRepo_Update: internal error", "id", job.ID, "err", result.Error)
+		return fmt.Errorf("%w: update job error: %v", domain.InternalError, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.RecordNotFound
+	}
+
+	r.log.Debug("JobRepo_Update: End!", "id", job.ID)
+	return nil
+}
+
+func (r *jobRepo) Read(ctx context.Context, jobID string) (domain.ParseJob, error) {
+	r.log.Debug("JobRepo_Read: Start!", "id", jobID)
+
+	var gormJob GormParseJob
+	if err := r.db.WithContext(ctx).Where("id = ?", jobID).First(&gormJob).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.log.Debug("JobRepo_Read: record not found", "id", jobID)
+			return domain.ParseJob{}, domain.RecordNotFound
+		}
+		r.log.Error("JobRepo_Read: internal error", "id", jobID, "err", err)
+		return domain.ParseJob{}, fmt.Errorf("%w: read job error: %v", domain.InternalError, err)
+	}
+
+	r.log.Debug("JobRepo_Read: End!", "id", jobID)
+	return toDomainParseJob(gormJob), nil
+}
+
+func (r *jobRepo) RunningOwners(ctx context.Context) ([]string, error) {
+	r.log.Debug("JobRepo_RunningOwners: Start!")
+
+	var owners []string
+	err := r.db.WithContext(ctx).Model(&GormParseJob{}).
+		Where("status = ?", domain.JobStatusRunning).
+		Distinct().
+		Pluck("owner", &owners).Error
+	if err != nil {
+		r.log.Error("JobRepo_RunningOwners: internal error", "err", err)
+		return nil, fmt.Errorf("%w: list running job owners error: %v", domain.InternalError, err)
+	}
+
+	r.log.Debug("JobRepo_RunningOwners: End!", "count", len(owners))
+	return owners, nil
+}
+
+func (r *jobRepo) InterruptOwner(ctx context.Context, owner string, at time.Time) (int, error) {
+	r.log.Debug("JobRepo_InterruptOwner: Start!", "owner", owner)
+
+	result := r.db.WithContext(ctx).Model(&GormParseJob{}).
+		Where("status = ? AND owner = ?", domain.JobStatusRunning, owner).
+		Updates(map[string]any{
+			"status": domain.JobStatusInterrupted,
+			"finished_at": at,
+		})
+	if result.Error != nil {
+		r.log.Error("JobRepo_InterruptOwner: internal error", "owner", owner, "err", result.Error)
+		return 0, fmt.Errorf("%w: interrupt running jobs error: %v", domain.InternalError, result.Error)
+	}
+
+	r.log.Debug("JobRepo_InterruptOwner: End!", "interrupted", result.RowsAffected)
+	return int(result.RowsAffected), nil
+}
+
+func (r *jobRepo) RequestCancel(ctx context.Context, jobID string) (bool, error) {
+	r.log.Debug("JobRepo_RequestCancel: Start!", "id", jobID)
+
+	result := r.db.WithContext(ctx).Model(&GormParseJob{}).
+		Where("id = ? AND status = ?", jobID, domain.JobStatusRunning).
+		Update("cancel_requested", true)
+	if result.Error != nil {
+		r.log.Error("JobRepo_RequestCancel: internal error", "id", jobID, "err", result.Error)
+		return false, fmt.Errorf("%w: request job cancel error: %v", domain.InternalError, result.Error)
+	}
+
+	r.log.Debug("JobRepo_RequestCancel: End!", "requested", result.RowsAffected > 0)
+	return result.RowsAffected > 0, nil
+}
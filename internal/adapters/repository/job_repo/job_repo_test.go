@@ -0,0 +1,99 @@
+package jobrepo
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/repotest"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+var (
+	db *gorm.DB
+	integration = repotest.Integration
+	jbRepo usecase.JobRepo
+	lg usecase.Logger
+)
+
+func TestMain(m *testing.M) {
+	repotest.Main(m, func(testDB *gorm.DB) {
+		db = testDB
+		lg = logger.NewLogger()
+		jbRepo = NewJobRepo(db, lg)
+	})
+}
+
+func TestJobRepo_Lifecycle(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	job := domain.ParseJob{
+		ID: "test-job-lifecycle",
+		Status: domain.JobStatusRunning,
+		Owner: "test-job-owner",
+		Source: "mr-moto",
+		CreatedAt: now,
+		StartedAt: &now,
+	}
+
+	if err := jbRepo.Create(ctx, job); err != nil {
+		t.Fatalf("create job error: %v", err)
+	}
+	defer db.Where("id = ?", job.ID).Delete(&GormParseJob{})
+
+	job.PagesFetched = 3
+	job.Upserted = 40
+	job.Errors = []string{"source \"mr-moto\": timeout"}
+	if err := jbRepo.Update(ctx, job); err != nil {
+		t.Fatalf("update job error: %v", err)
+	}
+
+	// Отмену ставит другой экземпляр, Update владельца ее не затирает
+	if requested, err := jbRepo.RequestCancel(ctx, job.ID); err != nil || !requested {
+		t.Fatalf("request cancel: %v, error: %v", requested, err)
+	}
+	if err := jbRepo.Update(ctx, job); err != nil {
+		t.Fatalf("update job error: %v", err)
+	}
+
+	owners, err := jbRepo.RunningOwners(ctx)
+	if err != nil || !slices.Contains(owners, job.Owner) {
+		t.Fatalf("running owners: %v, error: %v", owners, err)
+	}
+
+	if interrupted, err := jbRepo.InterruptOwner(ctx, "test-job-other-owner", time.Now()); err != nil || interrupted != 0 {
+		t.Fatalf("interrupt other owner: %d, error: %v", interrupted, err)
+	}
+	interrupted, err := jbRepo.InterruptOwner(ctx, job.Owner, time.Now())
+	if err != nil || interrupted != 1 {
+		t.Fatalf("interrupt owner: %d, error: %v", interrupted, err)
+	}
+
+	stored, err := jbRepo.Read(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("read job error: %v", err)
+	}
+	if stored.Status != domain.JobStatusInterrupted || stored.FinishedAt == nil || !stored.CancelRequested {
+		t.Errorf("job is not interrupted: %+v", stored)
+	}
+	if stored.PagesFetched != 3 || stored.Upserted != 40 || len(stored.Errors) != 1 {
+		t.Errorf("job progress is not saved: %+v", stored)
+	}
+
+	if requested, err := jbRepo.RequestCancel(ctx, job.ID); err != nil || requested {
+		t.Errorf("cancel of finished job requested: %v, error: %v", requested, err)
+	}
+
+	if _, err := jbRepo.Read(ctx, "test-job-missing"); err != domain.RecordNotFound {
+		t.Errorf("expected record not found, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package jobrepo
+
+import (
+	"encoding/json"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+func toDomainParseJob(job GormParseJob) domain.ParseJob {
+	var errs []string
+	// Битый json в errors не повод не отдавать задачу
+	_ = json.Unmarshal([]byte(job.Errors), &errs)
+
+	return domain.ParseJob{
+		ID: job.ID,
+		Status: job.Status,
+		Owner: job.Owner,
+		CancelRequested: job.CancelRequested,
+		Source: job.Source,
+		WithDetails: job.WithDetails,
+		Snapshot: job.Snapshot,
+		Force: job.Force,
+		PagesFetched: job.PagesFetched,
+		Upserted: job.Upserted,
+		Failed: job.Failed,
+		Deactivated: job.Deactivated,
+		Errors: errs,
+		CreatedAt: job.CreatedAt,
+		StartedAt: job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+func toGormParseJob(job domain.ParseJob) GormParseJob {
+	errs := job.Errors
+	if errs == nil {
+		errs = []string{}
+	}
+	data, _ := json.Marshal(errs)
+
+	return GormParseJob{
+		ID: job.ID,
+		Status: job.Status,
+		Owner: job.Owner,
+		CancelRequested: job.CancelRequested,
+		Source: job.Source,
+		WithDetails: job.WithDetails,
+		Snapshot: job.Snapshot,
+		Force: job.Force,
+		PagesFetched: job.PagesFetched,
+		Upserted: job.Upserted,
+		Failed: job.Failed,
+		Deactivated: job.Deactivated,
+		Errors: string(data),
+		CreatedAt: job.CreatedAt,
+		StartedAt: job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
@@ -0,0 +1,28 @@
+package jobrepo
+
+import (
+	"time"
+)
+
+type GormParseJob struct {
+	ID string `gorm:"type:varchar(64);primaryKey"`
+	Status string `gorm:"type:varchar(20);index"`
+	Owner string `gorm:"type:varchar(64)"`
+	CancelRequested bool
+	Source string `gorm:"type:varchar(100)"`
+	WithDetails bool
+	Snapshot string `gorm:"type:varchar(100)"`
+	Force bool
+	PagesFetched int
+	Upserted int
+	Failed int
+	Deactivated int
+	Errors string `gorm:"type:text"` // json-массив строк
+	CreatedAt time.Time
+	StartedAt *time.Time
+	FinishedAt *time.Time
+}
+
+func (GormParseJob) TableName() string {
+	return "parse_jobs"
+}
@@ -0,0 +1,77 @@
+package lockrepo
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+/*
+lockRepo - блокировки на advisory lock Postgres. Блокировка уровня сессии,
+поэтому под каждую берется отдельное соединение из пула и держится до
+unlock. Если экземпляр приложения упадет, соединение закроется и Postgres
+сам снимет блокировку - зависших блокировок, как у таблицы с арендой, нет.
+*/
+type lockRepo struct {
+	db *gorm.DB
+	log usecase.Logger
+}
+
+func NewLockRepo(db *gorm.DB, log usecase.Logger) usecase.LockRepo {
+	return &lockRepo{
+		db: db,
+		log: log,
+	}
+}
+
+func (r *lockRepo) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	r.log.Debug("LockRepo_TryLock: Start!", "name", name)
+
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: get sql db error: %v", domain.InternalError, err)
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		r.log.Error("LockRepo_TryLock: get connection error", "name", name, "err", err)
+		return nil, false, fmt.Errorf("%w: get connection error: %v", domain.InternalError, err)
+	}
+
+	key := lockKey(name)
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		conn.Close()
+		r.log.Error("LockRepo_TryLock: lock error", "name", name, "err", err)
+		return nil, false, fmt.Errorf("%w: advisory lock error: %v", domain.InternalError, err)
+	}
+	if !locked {
+		conn.Close()
+		r.log.Debug("LockRepo_TryLock: lock is held by another session", "name", name)
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// ctx запроса к этому моменту может быть уже отменен
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			r.log.Error("LockRepo_TryLock: unlock error", "name", name, "err", err)
+		}
+		conn.Close()
+	}
+
+	r.log.Debug("LockRepo_TryLock: End!", "name", name)
+	return unlock, true, nil
+}
+
+// lockKey переводит имя блокировки в ключ advisory lock.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("electoral_system:" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,53 @@
+package lockrepo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/repotest"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+var (
+	db *gorm.DB
+	integration = repotest.Integration
+	lkRepo usecase.LockRepo
+	lg usecase.Logger
+)
+
+func TestMain(m *testing.M) {
+	repotest.Main(m, func(testDB *gorm.DB) {
+		db = testDB
+		lg = logger.NewLogger()
+		lkRepo = NewLockRepo(db, lg)
+	})
+}
+
+func TestLockRepo_TryLock(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+
+	unlock, ok, err := lkRepo.TryLock(ctx, "test-lock")
+	if err != nil || !ok {
+		t.Fatalf("first lock: ok %v, error: %v", ok, err)
+	}
+
+	// Второй экземпляр приложения - это другое соединение, как и здесь
+	if _, ok, err := lkRepo.TryLock(ctx, "test-lock"); err != nil || ok {
+		t.Fatalf("second lock: ok %v, error: %v", ok, err)
+	}
+
+	unlock()
+
+	unlock, ok, err = lkRepo.TryLock(ctx, "test-lock")
+	if err != nil || !ok {
+		t.Fatalf("lock after unlock: ok %v, error: %v", ok, err)
+	}
+	unlock()
+}
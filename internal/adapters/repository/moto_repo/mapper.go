@@ -7,30 +7,115 @@ import (
 func toDomainMoto(moto GormMoto) domain.Moto {
 	return domain.Moto{
 		ID: moto.ID,
+		ExternalID: toDomainExternalID(moto.ExternalID),
+		Source: moto.Source,
 		Name: moto.Name,
+		Brand: moto.Brand,
+		Model: moto.Model,
+		Trim: moto.Trim,
 		Year: moto.Year,
 		Mileage: moto.Mileage,
+		MileageOriginal: moto.MileageOriginal,
+		MileageUnit: moto.MileageUnit,
 		EngineSize: moto.EngineSize,
+		EngineSizeUnit: moto.EngineSizeUnit,
+		Powertrain: moto.Powertrain,
+		MotorPowerKW: moto.MotorPowerKW,
 		MotoType: moto.MotoType,
+		Class: domain.MotoClass(moto.Class),
 		Location: moto.Location,
+		SalonID: moto.SalonID,
 		Price: moto.Price,
+		OldPrice: moto.OldPrice,
+		PriceCurrency: moto.PriceCurrency,
+		PriceStatus: moto.PriceStatus,
+		PreviousPrice: moto.PreviousPrice,
+		PriceChangedAt: moto.PriceChangedAt,
+		SourceURL: moto.SourceURL,
+		ImageURL: moto.ImageURL,
+		DealerURL: moto.DealerURL,
+		Horsepower: moto.Horsepower,
+		Weight: moto.Weight,
+		Color: moto.Color,
+		Transmission: moto.Transmission,
+		ABS: moto.ABS,
+		Description: moto.Description,
+		DetailsParsedAt: moto.DetailsParsedAt,
+		LastSeenAt: moto.LastSeenAt,
+		DeactivatedAt: moto.DeactivatedAt,
 		CreatedAt: moto.CreatedAt,
 		UpdatedAt: moto.UpdatedAt,
 	}
 }
 
+func toDomainExternalID(externalID *string) string {
+	if externalID == nil {
+		return ""
+	}
+	return *externalID
+}
+
+// Пустой ключ пишем как NULL, иначе уникальный индекс не даст создать
+// больше одной записи без ключа.
+func toGormExternalID(externalID string) *string {
+	if externalID == "" {
+		return nil
+	}
+	return &externalID
+}
+
 func toGormMoto(moto domain.Moto) GormMoto {
 	return GormMoto{
 		ID: moto.ID,
+		ExternalID: toGormExternalID(moto.ExternalID),
+		Source: moto.Source,
 		Name: moto.Name,
+		Brand: moto.Brand,
+		Model: moto.Model,
+		Trim: moto.Trim,
 		Year: moto.Year,
 		Mileage: moto.Mileage,
+		MileageOriginal: moto.MileageOriginal,
+		MileageUnit: moto.MileageUnit,
 		EngineSize: moto.EngineSize,
+		EngineSizeUnit: moto.EngineSizeUnit,
+		Powertrain: moto.Powertrain,
+		MotorPowerKW: moto.MotorPowerKW,
 		MotoType: moto.MotoType,
+		Class: string(moto.Class),
 		Location: moto.Location,
+		SalonID: moto.SalonID,
 		Price: moto.Price,
+		OldPrice: moto.OldPrice,
+		PriceCurrency: moto.PriceCurrency,
+		PriceStatus: moto.PriceStatus,
+		PreviousPrice: moto.PreviousPrice,
+		PriceChangedAt: moto.PriceChangedAt,
+		SourceURL: moto.SourceURL,
+		ImageURL: moto.ImageURL,
+		DealerURL: moto.DealerURL,
+		Horsepower: moto.Horsepower,
+		Weight: moto.Weight,
+		Color: moto.Color,
+		Transmission: moto.Transmission,
+		ABS: moto.ABS,
+		Description: moto.Description,
+		DetailsParsedAt: moto.DetailsParsedAt,
+		LastSeenAt: moto.LastSeenAt,
+		DeactivatedAt: moto.DeactivatedAt,
 		CreatedAt: moto.CreatedAt,
 		UpdatedAt: moto.UpdatedAt,
 	}
 }
 
+
+func toDomainPricePoint(point GormPricePoint) domain.PricePoint {
+	return domain.PricePoint{
+		MotoID: point.MotoID,
+		Price: point.Price,
+		OldPrice: point.OldPrice,
+		PriceCurrency: point.PriceCurrency,
+		PriceStatus: point.PriceStatus,
+		RecordedAt: point.RecordedAt,
+	}
+}
@@ -8,13 +8,43 @@ import (
 
 type GormMoto struct {
 	ID uint `gorm:"primaryKey;autoIncrement;unique"`
+	ExternalID *string `gorm:"type:varchar(512);uniqueIndex:idx_motos_external_id"`
+	PreviousExternalID *string `gorm:"type:varchar(512)"` // ключ до RekeyListings
+	Source string `gorm:"type:varchar(100);index"`
 	Name string `gorm:"type:varchar(100)"`
+	Brand string `gorm:"type:varchar(100)"`
+	Model string `gorm:"type:varchar(100)"`
+	Trim string `gorm:"type:varchar(255)"`
 	Year int `gorm:"not null"`
 	Mileage int `gorm:"not null"`
+	MileageOriginal int `gorm:"not null;default:0"`
+	MileageUnit string `gorm:"type:varchar(10)"`
 	EngineSize int `gorm:"not null"`
+	EngineSizeUnit string `gorm:"type:varchar(10)"`
+	Powertrain string `gorm:"type:varchar(20);index"`
+	MotorPowerKW float64 `gorm:"column:motor_power_kw;not null;default:0"`
 	MotoType string	`gorm:"type:varchar(255)"`
+	Class string `gorm:"column:moto_class;type:varchar(30);index"`
 	Location string `gorm:"type:varchar(255)"`
+	SalonID *uint `gorm:"index"`
 	Price int64 `gorm:"not null"`
+	OldPrice int64 `gorm:"not null;default:0"`
+	PriceCurrency string `gorm:"type:varchar(3)"`
+	PriceStatus string `gorm:"type:varchar(20);index"`
+	PreviousPrice int64 `gorm:"not null;default:0"`
+	PriceChangedAt *time.Time
+	SourceURL string `gorm:"type:varchar(512)"`
+	ImageURL string `gorm:"type:varchar(512)"`
+	DealerURL string `gorm:"type:varchar(512)"`
+	Horsepower int
+	Weight int
+	Color string `gorm:"type:varchar(100)"`
+	Transmission string `gorm:"type:varchar(100)"`
+	ABS *bool `gorm:"column:abs"`
+	Description string `gorm:"type:text"`
+	DetailsParsedAt *time.Time
+	LastSeenAt *time.Time
+	DeactivatedAt *time.Time `gorm:"index"`
 	CreatedAt *time.Time
 	UpdatedAt *time.Time
 	DeletedAt *gorm.DeletedAt `gorm:"index"`
@@ -23,3 +53,17 @@ type GormMoto struct {
 func (GormMoto) TableName() string {
 	return "motos"
 }
+
+type GormPricePoint struct {
+	ID uint `gorm:"primaryKey;autoIncrement"`
+	MotoID uint `gorm:"not null;index:idx_moto_price_history_moto_id_recorded_at,priority:1"`
+	Price int64 `gorm:"not null"`
+	OldPrice int64 `gorm:"not null;default:0"`
+	PriceCurrency string `gorm:"type:varchar(3)"`
+	PriceStatus string `gorm:"type:varchar(20)"`
+	RecordedAt time.Time `gorm:"not null;index:idx_moto_price_history_moto_id_recorded_at,priority:2"`
+}
+
+func (GormPricePoint) TableName() string {
+	return "moto_price_history"
+}
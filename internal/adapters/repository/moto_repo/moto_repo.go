@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"context"
 	"errors"
+	"slices"
+	"time"
 
 	"github.com/vvetta/electoral_system/internal/domain"
 	"github.com/vvetta/electoral_system/internal/usecase"
@@ -12,6 +14,50 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// Колонки, которые перезаписываются при upsert.
+var motoUpsertColumns = []string{
+	"source",
+	"year",
+	"name",
+	"brand",
+	"model",
+	"trim",
+	"mileage",
+	"mileage_original",
+	"mileage_unit",
+	"engine_size",
+	"engine_size_unit",
+	"powertrain",
+	"motor_power_kw",
+	"moto_type",
+	"moto_class",
+	"location",
+	"salon_id",
+	"price",
+	"old_price",
+	"price_currency",
+	"price_status",
+	"source_url",
+	"image_url",
+	"dealer_url",
+	"last_seen_at",
+	"deactivated_at", // объявление снова в каталоге - возвращаем его в показ
+	"deleted_at", // удаленное объявление снова пришло с обходом - восстанавливаем
+	"updated_at",
+}
+
+// Колонки со страницы объявления. Обновляются только если детали
+// были получены в этом обходе, иначе быстрый обход без деталей их бы затер.
+var motoDetailsUpsertColumns = []string{
+	"horsepower",
+	"weight",
+	"color",
+	"transmission",
+	"abs",
+	"description",
+	"details_parsed_at",
+}
+
 type motoRepo struct {
 	db *gorm.DB
 	log usecase.Logger
@@ -70,25 +116,31 @@ func (r *motoRepo) Read(ctx context.Context, motoID uint) (domain.Moto, error) {
 	return domainMoto, nil
 }
 
+/*
+Update работает как upsert.
+Если у мотоцикла есть ExternalID, конфликт ищется по нему, чтобы повторный
+обход каталога обновлял то же объявление. Иначе - по первичному ключу.
+*/
 func (r *motoRepo) Update(ctx context.Context, moto domain.Moto) (domain.Moto, error) {
 	r.log.Debug("MotoRepo_Update: Start!")	
 
 	var gormMoto GormMoto
 	gormMoto = toGormMoto(moto)
 
+	conflictColumn := "id"
+	if gormMoto.ExternalID != nil {
+		conflictColumn = "external_id"
+	}
+
+	upsertColumns := motoUpsertColumns
+	if gormMoto.DetailsParsedAt != nil {
+		upsertColumns = append(append([]string{}, motoUpsertColumns...), motoDetailsUpsertColumns...)
+	}
+
 	result := r.db.WithContext(ctx).Clauses(
 		clause.OnConflict{
-			Columns: []clause.Column{{Name: "id"}},
-			DoUpdates: clause.AssignmentColumns([]string{
-				"year",
-				"name",
-				"mileage",
-				"engine_size",
-				"moto_type",
-				"location",
-				"price",
-				"updated_at",
-			}),
+			Columns: []clause.Column{{Name: conflictColumn}},
+			DoUpdates: clause.AssignmentColumns(upsertColumns),
 		},
 	).Create(&gormMoto)
 
@@ -107,8 +159,14 @@ func (r *motoRepo) Update(ctx context.Context, moto domain.Moto) (domain.Moto, e
 	}
 	r.log.Debug("MotoRepo_Update: record update success!", "id", gormMoto.ID)
 
+	// Upsert идет мимо soft delete, читаем строку так же
+	query := r.db.WithContext(ctx).Unscoped().Where("id = ?", gormMoto.ID)
+	if gormMoto.ExternalID != nil {
+		query = r.db.WithContext(ctx).Unscoped().Where("external_id = ?", *gormMoto.ExternalID)
+	}
+
 	var update GormMoto
-	if err := r.db.WithContext(ctx).Where("id = ?", gormMoto.ID).First(&update).Error; err != nil {
+	if err := query.First(&update).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			r.log.Error("MotoRepo_Update: updated record not found!", "id", gormMoto.ID)
 			return domain.Moto{}, domain.RecordNotFound
@@ -123,6 +181,217 @@ func (r *motoRepo) Update(ctx context.Context, moto domain.Moto) (domain.Moto, e
 	return domainMoto, nil
 }
 
+/*
+UpsertMany пишет пачку мотоциклов одним INSERT ... ON CONFLICT (external_id)
+внутри транзакции. Детали объявления обновляются только у тех строк,
+где они пришли (details_parsed_at не NULL), чтобы в одной пачке можно было
+смешивать мотоциклы с деталями и без.
+В той же транзакции в moto_price_history пишется цена новых объявлений
+и тех, у кого обход ее изменил, а у мотоцикла запоминается прошлая цена.
+*/
+func (r *motoRepo) UpsertMany(
+	ctx context.Context,
+	motos []domain.Moto,
+	opts domain.UpsertOptions,
+) (domain.UpsertStats, error) {
+	r.log.Debug("MotoRepo_UpsertMany: Start!", "size", len(motos), "record_price_history", opts.RecordPriceHistory)
+
+	var stats domain.UpsertStats
+	if len(motos) == 0 {
+		return stats, nil
+	}
+
+	// В одном INSERT ... ON CONFLICT строка не может обновиться дважды,
+	// поэтому дубли ключа внутри пачки схлопываем, оставляя последний
+	index := make(map[string]int, len(motos))
+	gormMotos := make([]GormMoto, 0, len(motos))
+	for _, moto := range motos {
+		if moto.ExternalID == "" {
+			r.log.Error("MotoRepo_UpsertMany: moto without external_id", "name", moto.Name)
+			return stats, fmt.Errorf("%w: moto without external_id", domain.InternalError)
+		}
+
+		if i, ok := index[moto.ExternalID]; ok {
+			gormMotos[i] = toGormMoto(moto)
+			continue
+		}
+		index[moto.ExternalID] = len(gormMotos)
+		gormMotos = append(gormMotos, toGormMoto(moto))
+	}
+
+	columns := motoUpsertColumns
+	if !opts.MarkSeen {
+		columns = slices.DeleteFunc(slices.Clone(columns), func(column string) bool {
+			return column == "last_seen_at" || column == "deactivated_at"
+		})
+	}
+
+	assignments := clause.AssignmentColumns(columns)
+	for _, column := range motoDetailsUpsertColumns {
+		assignments = append(assignments, clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value: gorm.Expr(fmt.Sprintf(
+				"CASE WHEN excluded.details_parsed_at IS NULL THEN motos.%[1]s ELSE excluded.%[1]s END",
+				column,
+			)),
+		})
+	}
+
+	now := time.Now()
+	if opts.RecordPriceHistory {
+		assignments = append(assignments,
+			clause.Assignment{
+				Column: clause.Column{Name: "previous_price"},
+				Value: gorm.Expr("CASE WHEN " + priceChangedSQL + " THEN motos.price ELSE motos.previous_price END"),
+			},
+			clause.Assignment{
+				Column: clause.Column{Name: "price_changed_at"},
+				Value: gorm.Expr("CASE WHEN " + priceChangedSQL + " THEN ?::timestamptz ELSE motos.price_changed_at END", now),
+			},
+		)
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		before, err := loadPrices(tx, index)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "external_id"}},
+				DoUpdates: assignments,
+			},
+		).Create(&gormMotos)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// Что было в базе до записи - обновлено, остальное - новые объявления
+		stats.Updated = len(before)
+		stats.Inserted = int(result.RowsAffected) - len(before)
+
+		if !opts.RecordPriceHistory {
+			return nil
+		}
+		return recordPriceChanges(tx, before, gormMotos, now)
+	})
+	if err != nil {
+		r.log.Error("MotoRepo_UpsertMany: internal error", "err", err)
+		return domain.UpsertStats{}, fmt.Errorf("%w: upsert motos error: %v", domain.InternalError, err)
+	}
+
+	r.log.Debug("MotoRepo_UpsertMany: End!", "inserted", stats.Inserted, "updated", stats.Updated)
+	return stats, nil
+}
+
+// Цена изменилась - то же сравнение, что и priceState в recordPriceChanges
+const priceChangedSQL = "(motos.price, motos.price_currency, motos.price_status) IS DISTINCT FROM (excluded.price, excluded.price_currency, excluded.price_status)"
+
+// Цена объявления до записи пачки, см. recordPriceChanges
+type priceState struct {
+	Price int64
+	PriceCurrency string
+	PriceStatus string
+}
+
+func loadPrices(tx *gorm.DB, index map[string]int) (map[string]priceState, error) {
+	externalIDs := make([]string, 0, len(index))
+	for externalID := range index {
+		externalIDs = append(externalIDs, externalID)
+	}
+
+	var rows []GormMoto
+	err := tx.Unscoped().
+		Select("external_id, price, price_currency, price_status").
+		Where("external_id IN ?", externalIDs).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]priceState, len(rows))
+	for _, row := range rows {
+		prices[*row.ExternalID] = priceState{row.Price, row.PriceCurrency, row.PriceStatus}
+	}
+	return prices, nil
+}
+
+// recordPriceChanges пишет в историю новые объявления и те, у кого поменялась цена.
+// ID мотоциклов к этому моменту заполнены из RETURNING после upsert.
+func recordPriceChanges(
+	tx *gorm.DB,
+	before map[string]priceState,
+	motos []GormMoto,
+	now time.Time,
+) error {
+	var points []GormPricePoint
+	for _, moto := range motos {
+		state := priceState{moto.Price, moto.PriceCurrency, moto.PriceStatus}
+		if prev, ok := before[*moto.ExternalID]; ok && prev == state {
+			continue
+		}
+
+		points = append(points, GormPricePoint{
+			MotoID: moto.ID,
+			Price: moto.Price,
+			OldPrice: moto.OldPrice,
+			PriceCurrency: moto.PriceCurrency,
+			PriceStatus: moto.PriceStatus,
+			RecordedAt: now,
+		})
+	}
+
+	if len(points) == 0 {
+		return nil
+	}
+	return tx.Create(&points).Error
+}
+
+func (r *motoRepo) GetPriceHistory(ctx context.Context, motoID uint) ([]domain.PricePoint, error) {
+	r.log.Debug("MotoRepo_GetPriceHistory: Start!", "id", motoID)
+
+	var points []GormPricePoint
+	err := r.db.WithContext(ctx).
+		Where("moto_id = ?", motoID).
+		Order("recorded_at, id").
+		Find(&points).Error
+	if err != nil {
+		r.log.Error("MotoRepo_GetPriceHistory: internal error", "id", motoID, "err", err)
+		return nil, fmt.Errorf("%w: get price history error: %v", domain.InternalError, err)
+	}
+
+	history := make([]domain.PricePoint, 0, len(points))
+	for _, point := range points {
+		history = append(history, toDomainPricePoint(point))
+	}
+
+	r.log.Debug("MotoRepo_GetPriceHistory: End!", "points", len(history))
+	return history, nil
+}
+
+func (r *motoRepo) DeactivateMissing(
+	ctx context.Context,
+	source string,
+	seenSince time.Time,
+) (int, error) {
+	r.log.Debug("MotoRepo_DeactivateMissing: Start!", "source", source, "seen_since", seenSince)
+
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&GormMoto{}).
+		Where("source = ? AND deactivated_at IS NULL", source).
+		Where("last_seen_at IS NULL OR last_seen_at < ?", seenSince).
+		Updates(map[string]any{"deactivated_at": now, "updated_at": now})
+	if result.Error != nil {
+		r.log.Error("MotoRepo_DeactivateMissing: internal error", "source", source, "err", result.Error)
+		return 0, fmt.Errorf("%w: deactivate motos error: %v", domain.InternalError, result.Error)
+	}
+
+	r.log.Debug("MotoRepo_DeactivateMissing: End!", "deactivated", result.RowsAffected)
+	return int(result.RowsAffected), nil
+}
+
 func (r *motoRepo) Delete(ctx context.Context, motoID uint) error {
 	r.log.Debug("MotoRepo_Delete: Start!")	
 
@@ -167,6 +436,18 @@ func (r *motoRepo) GetMotosByFilter(
 
 func MotoFilterScope(f domain.MotoFilter) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
+		if !f.IncludeInactive {
+			db = db.Where("deactivated_at IS NULL")
+		}
+
+		if f.PriceDropped {
+			db = db.Where("previous_price > price AND price > 0 AND price_changed_at >= ?", time.Now().Add(-domain.RecentPriceDropWindow))
+		}
+
+		// У электромотоциклов объема нет, 0 cc не должен попадать в "до 250"
+		if f.EngineSizeMin != nil || f.EngineSizeMax != nil {
+			db = db.Where("powertrain = ?", domain.PowertrainICE)
+		}
 		if f.EngineSizeMin != nil {
 			db = db.Where("engine_size >= ?", *f.EngineSizeMin)
 		}
@@ -181,6 +462,9 @@ func MotoFilterScope(f domain.MotoFilter) func(db *gorm.DB) *gorm.DB {
 			db = db.Where("year < ?", *f.YearMax)
 		}
 
+		if f.MileageMin != nil || f.MileageMax != nil {
+			db = db.Where("mileage_unit <> ?", domain.MileageUnitHours)
+		}
 		if f.MileageMin != nil {
 			db = db.Where("mileage >= ?", *f.MileageMin)
 		}
@@ -188,6 +472,16 @@ func MotoFilterScope(f domain.MotoFilter) func(db *gorm.DB) *gorm.DB {
 			db = db.Where("mileage < ?", *f.MileageMax)
 		}
 
+		// Цена 0 у объявления "по запросу" - не бесплатный мотоцикл,
+		// в подбор по бюджету такие не попадают
+		if f.PriceMin != nil || f.PriceMax != nil {
+			currency := f.PriceCurrency
+			if currency == "" {
+				currency = domain.PriceCurrencyRUB
+			}
+			db = db.Where("price_status IN ? AND price > 0", []string{domain.PriceStatusFixed, domain.PriceStatusFrom})
+			db = db.Where("price_currency = ?", currency)
+		}
 		if f.PriceMin != nil {
 			db = db.Where("price >= ?", *f.PriceMin)
 		}
@@ -195,8 +489,26 @@ func MotoFilterScope(f domain.MotoFilter) func(db *gorm.DB) *gorm.DB {
 			db = db.Where("price <= ?", *f.PriceMax)
 		}
 
-		if f.MotoType != "-" {
-			db = db.Where("moto_type = ?", f.MotoType)
+		if f.Class != "" {
+			db = db.Where("moto_class = ?", f.Class)
+		}
+
+		if f.Brand != "" {
+			db = db.Where("LOWER(brand) = LOWER(?)", f.Brand)
+		}
+		if f.Model != "" {
+			db = db.Where("LOWER(model) = LOWER(?)", f.Model)
+		}
+
+		if f.SalonID != nil {
+			db = db.Where("salon_id = ?", *f.SalonID)
+		}
+		if f.City != "" {
+			db = db.Where("salon_id IN (SELECT id FROM salons WHERE LOWER(city) = LOWER(?))", f.City)
+		}
+
+		if f.Powertrain != "" {
+			db = db.Where("powertrain = ?", f.Powertrain)
 		}
 
 		return db
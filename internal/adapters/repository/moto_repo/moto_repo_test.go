@@ -2,62 +2,33 @@ package motorepo
 
 import (
 	"context"
-	"flag"
-	"log"
-	"os"
+	"slices"
+	"strings"
 	"testing"
 	"errors"
+	"time"
 
 	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/repotest"
 	"github.com/vvetta/electoral_system/internal/domain"
 	"github.com/vvetta/electoral_system/internal/usecase"
 
-	"github.com/joho/godotenv"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var (
 	db *gorm.DB
-	integration = flag.Bool("integration", false, "run integration tests")
+	integration = repotest.Integration
 	mtRepo usecase.MotoRepo
 	lg usecase.Logger
 )
 
 func TestMain(m *testing.M) {
-	flag.Parse()
-
-	_ = godotenv.Load(".env")
-
-	if *integration {
-		var err error
-
-		testDSN := getTestDSN()
-		db, err = gorm.Open(postgres.Open(testDSN), &gorm.Config{})
-		if err != nil {
-			log.Fatalf("error connetc to test db: %s", testDSN)			
-		}	
-
+	repotest.Main(m, func(testDB *gorm.DB) {
+		db = testDB
 		lg = logger.NewLogger()
 		mtRepo = NewMotoRepo(db, lg)
-	}
-
-	code := m.Run()
-	os.Exit(code)
-}
-
-func getTestDSN() string {
-	var dsn string
-
-	DB_USER := os.Getenv("PG_TEST_USER")
-	DB_PASS := os.Getenv("PG_TEST_PASSWORD")
-	DB_HOST := os.Getenv("PG_TEST_HOST")
-	DB_PORT := os.Getenv("PG_TEST_PORT")
-	DB_NAME := os.Getenv("PG_TEST_DB_NAME")
-
-	dsn = "postgres://" + DB_USER + ":" + DB_PASS + "@" + DB_HOST + ":" + DB_PORT + "/" + DB_NAME + "?sslmode=disable"	
-
-	return dsn
+	})
 }
 
 func TestMotoRepo_Crud(t *testing.T) {
@@ -133,3 +104,452 @@ func TestMotoRepo_Crud(t *testing.T) {
 	}
 }
 
+func TestMotoRepo_UpdateByExternalID(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+
+	testMoto := domain.Moto{
+		ExternalID: "test-external-id",
+		Name: "Honda",
+		Year: 2015,
+		Mileage: 12000,
+		MotoType: "Спорт",
+		Class: domain.MotoClassSport,
+		Location: "ВДНХ",
+		EngineSize: 600,
+		Price: int64(700000),
+	}
+
+	first, err := mtRepo.Update(ctx, testMoto)
+	if err != nil {
+		t.Fatalf("first upsert error: %v", err)
+	}
+
+	testMoto.Price = int64(650000)
+	second, err := mtRepo.Update(ctx, testMoto)
+	if err != nil {
+		t.Fatalf("second upsert error: %v", err)
+	}
+
+	if first.ID != second.ID {
+		t.Errorf("upsert by external_id created new record: %d != %d", first.ID, second.ID)
+	}
+
+	if second.Price != testMoto.Price {
+		t.Errorf("price not updated: %d", second.Price)
+	}
+
+	if err := mtRepo.Delete(ctx, second.ID); err != nil {
+		t.Errorf("delete moto error: %v", err)
+	}
+}
+
+func TestMotoRepo_UpsertRestoresDeleted(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+	source := "test-upsert-deleted"
+	defer db.Unscoped().Where("source = ?", source).Delete(&GormMoto{})
+
+	testMoto := domain.Moto{ExternalID: "test-upsert-deleted-1", Source: source, Name: "Honda", Price: 100}
+	created, err := mtRepo.Update(ctx, testMoto)
+	if err != nil {
+		t.Fatalf("create moto error: %v", err)
+	}
+
+	// Удаленное объявление снова пришло с обходом через Update
+	if err := mtRepo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("delete moto error: %v", err)
+	}
+	testMoto.Price = 110
+	restored, err := mtRepo.Update(ctx, testMoto)
+	if err != nil {
+		t.Fatalf("upsert deleted moto error: %v", err)
+	}
+	if restored.ID != created.ID || restored.Price != 110 {
+		t.Errorf("deleted moto is not restored: %+v", restored)
+	}
+	if _, err := mtRepo.Read(ctx, created.ID); err != nil {
+		t.Errorf("restored moto is still hidden: %v", err)
+	}
+
+	// И через UpsertMany
+	if err := mtRepo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("delete moto error: %v", err)
+	}
+	stats, err := mtRepo.UpsertMany(ctx, []domain.Moto{testMoto}, domain.UpsertOptions{MarkSeen: true})
+	if err != nil {
+		t.Fatalf("upsert many deleted moto error: %v", err)
+	}
+	if stats.Inserted != 0 || stats.Updated != 1 {
+		t.Errorf("unexpected stats for a deleted moto: %+v", stats)
+	}
+	if _, err := mtRepo.Read(ctx, created.ID); err != nil {
+		t.Errorf("moto restored by upsert many is still hidden: %v", err)
+	}
+}
+
+func TestMotoRepo_GetMotosByFilter_SkipsUnpriced(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+
+	priced, err := mtRepo.Update(ctx, domain.Moto{
+		ExternalID: "test-priced",
+		Name: "Suzuki",
+		Year: 2018,
+		MotoType: "Спорт",
+		Class: domain.MotoClassSport,
+		Price: int64(500000),
+		PriceCurrency: domain.PriceCurrencyRUB,
+		PriceStatus: domain.PriceStatusFixed,
+	})
+	if err != nil {
+		t.Fatalf("create priced moto error: %v", err)
+	}
+	defer mtRepo.Delete(ctx, priced.ID)
+
+	onRequest, err := mtRepo.Update(ctx, domain.Moto{
+		ExternalID: "test-on-request",
+		Name: "Suzuki",
+		Year: 2018,
+		MotoType: "Спорт",
+		Class: domain.MotoClassSport,
+		PriceStatus: domain.PriceStatusOnRequest,
+	})
+	if err != nil {
+		t.Fatalf("create on request moto error: %v", err)
+	}
+	defer mtRepo.Delete(ctx, onRequest.ID)
+
+	// 5 000 долларов - не 5 000 рублей
+	inDollars, err := mtRepo.Update(ctx, domain.Moto{
+		ExternalID: "test-in-dollars",
+		Name: "Suzuki",
+		Year: 2018,
+		MotoType: "Спорт",
+		Class: domain.MotoClassSport,
+		Price: int64(5000),
+		PriceCurrency: "USD",
+		PriceStatus: domain.PriceStatusFixed,
+	})
+	if err != nil {
+		t.Fatalf("create moto priced in dollars error: %v", err)
+	}
+	defer mtRepo.Delete(ctx, inDollars.ID)
+
+	priceMax := int64(600000)
+	motos, err := mtRepo.GetMotosByFilter(ctx, domain.NewMotoFilter(0, 0, 0, &priceMax, domain.MotoClassSport))
+	if err != nil {
+		t.Fatalf("get motos by filter error: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, m := range motos {
+		found[m.ExternalID] = true
+	}
+	if !found[priced.ExternalID] {
+		t.Errorf("priced moto not found")
+	}
+	if found[onRequest.ExternalID] {
+		t.Errorf("moto without price matched price filter")
+	}
+	if found[inDollars.ExternalID] {
+		t.Errorf("moto priced in another currency matched price filter")
+	}
+}
+
+func TestMotoRepo_DeactivateMissing(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+	source := "test-reconcile"
+	before := time.Now().Add(-time.Hour)
+	crawl := time.Now()
+	crawlOpts := domain.UpsertOptions{MarkSeen: true}
+
+	_, err := mtRepo.UpsertMany(ctx, []domain.Moto{
+		{ExternalID: "test-reconcile-seen", Source: source, Name: "Honda", LastSeenAt: &crawl},
+		{ExternalID: "test-reconcile-gone", Source: source, Name: "Suzuki", LastSeenAt: &before},
+	}, crawlOpts)
+	if err != nil {
+		t.Fatalf("upsert motos error: %v", err)
+	}
+	defer db.Unscoped().Where("source = ?", source).Delete(&GormMoto{})
+
+	deactivated, err := mtRepo.DeactivateMissing(ctx, source, crawl)
+	if err != nil || deactivated != 1 {
+		t.Fatalf("deactivate missing: %d, error: %v", deactivated, err)
+	}
+
+	found := func(filter domain.MotoFilter) map[string]bool {
+		motos, err := mtRepo.GetMotosByFilter(ctx, filter)
+		if err != nil {
+			t.Fatalf("get motos by filter error: %v", err)
+		}
+		ids := make(map[string]bool)
+		for _, m := range motos {
+			ids[m.ExternalID] = true
+		}
+		return ids
+	}
+
+	if ids := found(domain.MotoFilter{}); !ids["test-reconcile-seen"] || ids["test-reconcile-gone"] {
+		t.Errorf("inactive moto is not hidden: %v", ids)
+	}
+	if ids := found(domain.MotoFilter{IncludeInactive: true}); !ids["test-reconcile-gone"] {
+		t.Errorf("inactive moto not found with IncludeInactive")
+	}
+
+	// Объявление снова появилось в каталоге
+	again := time.Now()
+	if _, err := mtRepo.UpsertMany(ctx, []domain.Moto{
+		{ExternalID: "test-reconcile-gone", Source: source, Name: "Suzuki", LastSeenAt: &again},
+	}, crawlOpts); err != nil {
+		t.Fatalf("upsert reappeared moto error: %v", err)
+	}
+	if ids := found(domain.MotoFilter{}); !ids["test-reconcile-gone"] {
+		t.Errorf("reappeared moto is still inactive")
+	}
+
+	// Запись из старого снимка не говорит, что объявление снова в каталоге
+	if _, err := mtRepo.DeactivateMissing(ctx, source, time.Now()); err != nil {
+		t.Fatalf("deactivate missing error: %v", err)
+	}
+	if _, err := mtRepo.UpsertMany(ctx, []domain.Moto{
+		{ExternalID: "test-reconcile-gone", Source: source, Name: "Suzuki"},
+	}, domain.UpsertOptions{}); err != nil {
+		t.Fatalf("upsert snapshot moto error: %v", err)
+	}
+	if ids := found(domain.MotoFilter{}); ids["test-reconcile-gone"] {
+		t.Errorf("snapshot write returned moto to the listing")
+	}
+}
+
+func TestMotoRepo_PriceHistory(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+	source := "test-price-history"
+	moto := domain.Moto{
+		ExternalID: "test-price-history-1",
+		Source: source,
+		Name: "Kawasaki",
+		Price: 1_000_000,
+		PriceCurrency: domain.PriceCurrencyRUB,
+		PriceStatus: domain.PriceStatusFixed,
+	}
+	live := domain.UpsertOptions{RecordPriceHistory: true}
+
+	if _, err := mtRepo.UpsertMany(ctx, []domain.Moto{moto}, live); err != nil {
+		t.Fatalf("upsert moto error: %v", err)
+	}
+	defer db.Unscoped().Where("source = ?", source).Delete(&GormMoto{})
+
+	// Повторный обход с той же ценой истории не добавляет
+	if _, err := mtRepo.UpsertMany(ctx, []domain.Moto{moto}, live); err != nil {
+		t.Fatalf("upsert same price error: %v", err)
+	}
+
+	// Снимок страниц со старой ценой историю не трогает
+	stale := moto
+	stale.Price = 1_200_000
+	if _, err := mtRepo.UpsertMany(ctx, []domain.Moto{stale}, domain.UpsertOptions{}); err != nil {
+		t.Fatalf("upsert stale price error: %v", err)
+	}
+
+	moto.Price = 900_000
+	if _, err := mtRepo.UpsertMany(ctx, []domain.Moto{moto}, live); err != nil {
+		t.Fatalf("upsert new price error: %v", err)
+	}
+
+	var stored GormMoto
+	if err := db.Where("external_id = ?", moto.ExternalID).First(&stored).Error; err != nil {
+		t.Fatalf("read stored moto error: %v", err)
+	}
+	if stored.PreviousPrice != 1_200_000 || stored.PriceChangedAt == nil {
+		t.Errorf("price change not recorded: previous %d, changed at %v", stored.PreviousPrice, stored.PriceChangedAt)
+	}
+
+	history, err := mtRepo.GetPriceHistory(ctx, stored.ID)
+	if err != nil {
+		t.Fatalf("get price history error: %v", err)
+	}
+	if len(history) != 2 || history[0].Price != 1_000_000 || history[1].Price != 900_000 {
+		t.Errorf("unexpected price history: %+v", history)
+	}
+
+	motos, err := mtRepo.GetMotosByFilter(ctx, domain.MotoFilter{PriceDropped: true})
+	if err != nil {
+		t.Fatalf("get motos by filter error: %v", err)
+	}
+	found := false
+	for _, m := range motos {
+		found = found || m.ExternalID == moto.ExternalID
+	}
+	if !found {
+		t.Errorf("moto with dropped price not found with PriceDropped")
+	}
+	// Та же сумма, но "от" - изменение и для истории, и для previous_price
+	moto.PriceStatus = domain.PriceStatusFrom
+	if _, err := mtRepo.UpsertMany(ctx, []domain.Moto{moto}, live); err != nil {
+		t.Fatalf("upsert new price status error: %v", err)
+	}
+	if err := db.Where("external_id = ?", moto.ExternalID).First(&stored).Error; err != nil {
+		t.Fatalf("read stored moto error: %v", err)
+	}
+	history, err = mtRepo.GetPriceHistory(ctx, stored.ID)
+	if err != nil || len(history) != 3 || stored.PreviousPrice != 900_000 {
+		t.Errorf("price status change: previous %d, history %+v, err %v", stored.PreviousPrice, history, err)
+	}
+}
+
+func TestMotoRepo_UpsertMany(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+	source := "test-upsert-many"
+	detailsAt := time.Now()
+	live := domain.UpsertOptions{MarkSeen: true, RecordPriceHistory: true}
+	defer db.Unscoped().Where("source = ?", source).Delete(&GormMoto{})
+
+	read := func(externalID string) GormMoto {
+		var stored GormMoto
+		if err := db.Where("external_id = ?", externalID).First(&stored).Error; err != nil {
+			t.Fatalf("read stored moto %s error: %v", externalID, err)
+		}
+		return stored
+	}
+
+	// Дубли ключа в пачке схлопываются, остается последний
+	stats, err := mtRepo.UpsertMany(ctx, []domain.Moto{
+		{ExternalID: "test-upsert-many-1", Source: source, Name: "Honda", Price: 100},
+		{ExternalID: "test-upsert-many-2", Source: source, Name: "Yamaha", Price: 200, Horsepower: 70, Color: "синий", DetailsParsedAt: &detailsAt},
+		{ExternalID: "test-upsert-many-1", Source: source, Name: "Honda CB", Price: 110},
+	}, live)
+	if err != nil {
+		t.Fatalf("upsert motos error: %v", err)
+	}
+	if stats.Inserted != 2 || stats.Updated != 0 {
+		t.Errorf("unexpected stats for a new batch: %+v", stats)
+	}
+	if first := read("test-upsert-many-1"); first.Name != "Honda CB" || first.Price != 110 {
+		t.Errorf("duplicate key is not resolved to the last moto: %+v", first)
+	}
+
+	// Обход без карточек не затирает характеристики, прочитанные раньше
+	stats, err = mtRepo.UpsertMany(ctx, []domain.Moto{
+		{ExternalID: "test-upsert-many-2", Source: source, Name: "Yamaha MT", Price: 200},
+		{ExternalID: "test-upsert-many-3", Source: source, Name: "Suzuki", Price: 300},
+	}, live)
+	if err != nil {
+		t.Fatalf("upsert motos without details error: %v", err)
+	}
+	if stats.Inserted != 1 || stats.Updated != 1 {
+		t.Errorf("unexpected stats for a mixed batch: %+v", stats)
+	}
+	second := read("test-upsert-many-2")
+	if second.Name != "Yamaha MT" || second.Horsepower != 70 || second.Color != "синий" || second.DetailsParsedAt == nil {
+		t.Errorf("details are lost without details_parsed_at: %+v", second)
+	}
+
+	// ID из RETURNING - и у новых, и у обновленных строк: история цены
+	// пишется на свой мотоцикл
+	if _, err := mtRepo.UpsertMany(ctx, []domain.Moto{
+		{ExternalID: "test-upsert-many-4", Source: source, Name: "Kawasaki", Price: 400},
+		{ExternalID: "test-upsert-many-1", Source: source, Name: "Honda CB", Price: 120},
+	}, live); err != nil {
+		t.Fatalf("upsert price change error: %v", err)
+	}
+	for externalID, want := range map[string][]int64{
+		"test-upsert-many-1": {110, 120},
+		"test-upsert-many-4": {400},
+	} {
+		history, err := mtRepo.GetPriceHistory(ctx, read(externalID).ID)
+		if err != nil {
+			t.Fatalf("get price history error: %v", err)
+		}
+		var prices []int64
+		for _, point := range history {
+			prices = append(prices, point.Price)
+		}
+		if !slices.Equal(prices, want) {
+			t.Errorf("price history of %s: %v, want %v", externalID, prices, want)
+		}
+	}
+}
+
+func TestMotoRepo_RekeyListings(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+	source := "test-rekey"
+	defer db.Unscoped().Where("source = ?", source).Delete(&GormMoto{})
+
+	// Название с NBSP по краям, как на сайте; ключи - хеши по старой формуле
+	listing := domain.Moto{Source: source, Name: " Honda CB 400 ", Year: 2018, EngineSize: 400, Location: "Москва"}
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+	oldKeys := []string{strings.Repeat("a", 64), strings.Repeat("b", 64)}
+	for i, seen := range []*time.Time{&older, &newer} {
+		row := toGormMoto(listing)
+		row.ExternalID = &oldKeys[i]
+		row.Mileage = 10_000 * (i + 1) // раньше пробег различал эти строки
+		row.LastSeenAt = seen
+		row.Price = int64(100 * (i + 1))
+		if err := db.Create(&row).Error; err != nil {
+			t.Fatalf("create moto with old key error: %v", err)
+		}
+		if err := db.Create(&GormPricePoint{MotoID: row.ID, Price: row.Price, RecordedAt: time.Now()}).Error; err != nil {
+			t.Fatalf("create price point error: %v", err)
+		}
+	}
+
+	stats, err := mtRepo.RekeyListings(ctx)
+	if err != nil {
+		t.Fatalf("rekey listings error: %v", err)
+	}
+	if stats.Rekeyed < 1 || stats.Merged < 1 {
+		t.Errorf("unexpected rekey stats: %+v", stats)
+	}
+
+	// Ключ из базы совпадает с тем, что посчитает обход
+	key := listing.ListingKey()
+	var stored GormMoto
+	if err := db.Where("external_id = ?", key).First(&stored).Error; err != nil {
+		t.Fatalf("read rekeyed moto error: %v", err)
+	}
+	if stored.Price != 200 || stored.PreviousExternalID == nil || *stored.PreviousExternalID != oldKeys[1] {
+		t.Errorf("fresher row is not kept with its old key: %+v", stored)
+	}
+	history, err := mtRepo.GetPriceHistory(ctx, stored.ID)
+	if err != nil || len(history) != 2 {
+		t.Errorf("price history of the merged row is not moved: %+v, err: %v", history, err)
+	}
+
+	listing.ExternalID = key
+	upserted, err := mtRepo.UpsertMany(ctx, []domain.Moto{listing}, domain.UpsertOptions{})
+	if err != nil || upserted.Inserted != 0 || upserted.Updated != 1 {
+		t.Errorf("crawl after rekey: %+v, err: %v", upserted, err)
+	}
+
+	// Повторный запуск ничего не меняет
+	if again, err := mtRepo.RekeyListings(ctx); err != nil || again.Merged != 0 {
+		t.Errorf("second rekey: %+v, err: %v", again, err)
+	}
+}
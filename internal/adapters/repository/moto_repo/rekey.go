@@ -0,0 +1,144 @@
+package motorepo
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// Ключ-хеш из domain.Moto.ListingKey. Ключи из ссылок и из id в файле дилера не пересчитываются
+const hashKeyPattern = "^[0-9a-f]{64}$"
+
+/*
+RekeyListings пересчитывает ключи-хеши объявлений по текущему
+domain.Moto.ListingKey. Ключ считается в Go, а не в SQL: только так он
+совпадет с тем, что посчитает следующий обход (TrimSpace и ToLower
+понимают NBSP и кириллицу независимо от локали базы).
+
+Старый ключ остается в previous_external_id, по нему откатывается миграция
+000020. Если под одним ключом оказалось несколько строк (раньше их различал
+пробег, или объявление потом нашлось по ссылке), остается самая свежая,
+история цен остальных переносится на нее, а сами они удаляются.
+Повторный запуск ничего не меняет.
+*/
+func (r *motoRepo) RekeyListings(ctx context.Context) (domain.RekeyStats, error) {
+	r.log.Debug("MotoRepo_RekeyListings: Start!")
+
+	var stats domain.RekeyStats
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var hashed []GormMoto
+		if err := tx.Unscoped().Where("external_id ~ ?", hashKeyPattern).Find(&hashed).Error; err != nil {
+			return err
+		}
+
+		newKeys := make(map[uint]string, len(hashed))
+		var changed []string
+		for _, row := range hashed {
+			key := toDomainMoto(row).ListingKey()
+			if key != *row.ExternalID {
+				newKeys[row.ID] = key
+				changed = append(changed, key)
+			}
+		}
+		if len(newKeys) == 0 {
+			return nil
+		}
+
+		// Строки, которые уже держат новые ключи: с ними пересчитанные сливаются
+		var holders []GormMoto
+		if err := tx.Unscoped().Where("external_id IN ?", changed).Find(&holders).Error; err != nil {
+			return err
+		}
+
+		groups := make(map[string][]GormMoto)
+		for _, row := range holders {
+			if _, ok := newKeys[row.ID]; !ok {
+				groups[*row.ExternalID] = append(groups[*row.ExternalID], row)
+			}
+		}
+		for _, row := range hashed {
+			if key, ok := newKeys[row.ID]; ok {
+				groups[key] = append(groups[key], row)
+			}
+		}
+
+		// Сначала удаляем лишние строки, иначе новый ключ упрется в уникальный индекс
+		for _, rows := range groups {
+			slices.SortFunc(rows, fresherFirst)
+			keeper := rows[0]
+			for _, row := range rows[1:] {
+				if err := tx.Model(&GormPricePoint{}).Where("moto_id = ?", row.ID).Update("moto_id", keeper.ID).Error; err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Delete(&GormMoto{}, row.ID).Error; err != nil {
+					return err
+				}
+				delete(newKeys, row.ID)
+				stats.Merged++
+			}
+		}
+
+		for _, row := range hashed {
+			key, ok := newKeys[row.ID]
+			if !ok {
+				continue
+			}
+			err := tx.Unscoped().Model(&GormMoto{}).Where("id = ?", row.ID).Updates(map[string]any{
+				"external_id": key,
+				"previous_external_id": *row.ExternalID,
+			}).Error
+			if err != nil {
+				return err
+			}
+			stats.Rekeyed++
+		}
+		return nil
+	})
+	if err != nil {
+		r.log.Error("MotoRepo_RekeyListings: internal error", "err", err)
+		return domain.RekeyStats{}, fmt.Errorf("%w: rekey listings error: %v", domain.InternalError, err)
+	}
+
+	r.log.Debug("MotoRepo_RekeyListings: End!", "rekeyed", stats.Rekeyed, "merged", stats.Merged)
+	return stats, nil
+}
+
+// fresherFirst ставит вперед живую строку, которую видели последней.
+func fresherFirst(a, b GormMoto) int {
+	if alive(a) != alive(b) {
+		if alive(a) {
+			return -1
+		}
+		return 1
+	}
+	if c := newerFirst(a.LastSeenAt, b.LastSeenAt); c != 0 {
+		return c
+	}
+	if c := newerFirst(a.UpdatedAt, b.UpdatedAt); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.ID, a.ID)
+}
+
+// newerFirst: более позднее время раньше, пустое - в конце.
+func newerFirst(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return b.Compare(*a)
+}
+
+func alive(m GormMoto) bool {
+	return m.DeletedAt == nil || !m.DeletedAt.Valid
+}
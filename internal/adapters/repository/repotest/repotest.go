@@ -0,0 +1,53 @@
+/*
+Package repotest - общая обвязка интеграционных тестов с базой:
+флаг -integration и подключение к тестовой базе из PG_TEST_*.
+*/
+package repotest
+
+import (
+	"flag"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Integration - включены ли интеграционные тесты: go test ./... -integration
+var Integration = flag.Bool("integration", false, "run integration tests")
+
+/*
+Main - TestMain пакета с интеграционными тестами. Если они включены,
+открывает тестовую базу и отдает ее setup, чтобы пакет собрал свои
+репозитории; затем запускает тесты.
+*/
+func Main(m *testing.M, setup func(db *gorm.DB)) {
+	flag.Parse()
+
+	_ = godotenv.Load(".env")
+
+	if *Integration {
+		testDSN := DSN()
+		db, err := gorm.Open(postgres.Open(testDSN), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("error connect to test db: %s", testDSN)
+		}
+
+		setup(db)
+	}
+
+	os.Exit(m.Run())
+}
+
+// DSN собирает строку подключения к тестовой базе из PG_TEST_*.
+func DSN() string {
+	DB_USER := os.Getenv("PG_TEST_USER")
+	DB_PASS := os.Getenv("PG_TEST_PASSWORD")
+	DB_HOST := os.Getenv("PG_TEST_HOST")
+	DB_PORT := os.Getenv("PG_TEST_PORT")
+	DB_NAME := os.Getenv("PG_TEST_DB_NAME")
+
+	return "postgres://" + DB_USER + ":" + DB_PASS + "@" + DB_HOST + ":" + DB_PORT + "/" + DB_NAME + "?sslmode=disable"
+}
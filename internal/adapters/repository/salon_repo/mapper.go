@@ -0,0 +1,35 @@
+package salonrepo
+
+import (
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+func toDomainSalon(salon GormSalon) domain.Salon {
+	return domain.Salon{
+		ID: salon.ID,
+		Name: salon.Name,
+		City: salon.City,
+		Address: salon.Address,
+		Latitude: salon.Latitude,
+		Longitude: salon.Longitude,
+		Phone: salon.Phone,
+		URL: salon.URL,
+		CreatedAt: salon.CreatedAt,
+		UpdatedAt: salon.UpdatedAt,
+	}
+}
+
+func toGormSalon(salon domain.Salon) GormSalon {
+	return GormSalon{
+		ID: salon.ID,
+		Name: salon.Name,
+		City: salon.City,
+		Address: salon.Address,
+		Latitude: salon.Latitude,
+		Longitude: salon.Longitude,
+		Phone: salon.Phone,
+		URL: salon.URL,
+		CreatedAt: salon.CreatedAt,
+		UpdatedAt: salon.UpdatedAt,
+	}
+}
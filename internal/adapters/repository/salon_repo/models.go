@@ -0,0 +1,22 @@
+package salonrepo
+
+import (
+	"time"
+)
+
+type GormSalon struct {
+	ID uint `gorm:"primaryKey;autoIncrement;unique"`
+	Name string `gorm:"type:varchar(255);uniqueIndex:idx_salons_name"`
+	City string `gorm:"type:varchar(100);index"`
+	Address string `gorm:"type:varchar(512)"`
+	Latitude *float64
+	Longitude *float64
+	Phone string `gorm:"type:varchar(50)"`
+	URL string `gorm:"type:varchar(512)"`
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+func (GormSalon) TableName() string {
+	return "salons"
+}
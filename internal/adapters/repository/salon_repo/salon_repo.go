@@ -0,0 +1,149 @@
+package salonrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Колонки справочника, которые перезаписываются при Upsert.
+var salonUpsertColumns = []string{
+	"city",
+	"address",
+	"latitude",
+	"longitude",
+	"phone",
+	"url",
+	"updated_at",
+}
+
+type salonRepo struct {
+	db *gorm.DB
+	log usecase.Logger
+}
+
+func NewSalonRepo(db *gorm.DB, log usecase.Logger) usecase.SalonRepo {
+	return &salonRepo{
+		db: db,
+		log: log,
+	}
+}
+
+/*
+GetOrCreate нужен парсеру: салон из объявления создается с одним именем
+(и ссылкой, если она есть), а адрес и контакты уже заполненного салона
+не трогаются. Ссылка дописывается, только если ее раньше не было.
+*/
+func (r *salonRepo) GetOrCreate(ctx context.Context, salon domain.Salon) (domain.Salon, error) {
+	r.log.Debug("SalonRepo_GetOrCreate: Start!", "name", salon.Name)
+
+	gormSalon := toGormSalon(salon)
+
+	err := r.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "name"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"url": gorm.Expr("COALESCE(NULLIF(salons.url, ''), excluded.url)"),
+			}),
+		},
+	).Create(&gormSalon).Error
+	if err != nil {
+		r.log.Error("SalonRepo_GetOrCreate: upsert error", "name", salon.Name, "err", err)
+		return domain.Salon{}, fmt.Errorf("%w: create salon error: %v", domain.InternalError, err)
+	}
+
+	result, err := r.getByName(ctx, salon.Name)
+	if err != nil {
+		return domain.Salon{}, err
+	}
+
+	r.log.Debug("SalonRepo_GetOrCreate: End!", "id", result.ID)
+	return result, nil
+}
+
+func (r *salonRepo) Upsert(ctx context.Context, salon domain.Salon) (domain.Salon, error) {
+	r.log.Debug("SalonRepo_Upsert: Start!", "name", salon.Name)
+
+	gormSalon := toGormSalon(salon)
+
+	err := r.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns(salonUpsertColumns),
+		},
+	).Create(&gormSalon).Error
+	if err != nil {
+		r.log.Error("SalonRepo_Upsert: upsert error", "name", salon.Name, "err", err)
+		return domain.Salon{}, fmt.Errorf("%w: upsert salon error: %v", domain.InternalError, err)
+	}
+
+	result, err := r.getByName(ctx, salon.Name)
+	if err != nil {
+		return domain.Salon{}, err
+	}
+
+	r.log.Debug("SalonRepo_Upsert: End!", "id", result.ID)
+	return result, nil
+}
+
+func (r *salonRepo) getByName(ctx context.Context, name string) (domain.Salon, error) {
+	var gormSalon GormSalon
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&gormSalon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			r.log.Error("SalonRepo_getByName: salon not found after upsert", "name", name)
+			return domain.Salon{}, domain.RecordNotFound
+		}
+		r.log.Error("SalonRepo_getByName: internal error", "name", name, "err", err)
+		return domain.Salon{}, fmt.Errorf("%w: get salon error: %v", domain.InternalError, err)
+	}
+
+	return toDomainSalon(gormSalon), nil
+}
+
+func (r *salonRepo) Read(ctx context.Context, salonID uint) (domain.Salon, error) {
+	r.log.Debug("SalonRepo_Read: Start!")
+
+	var gormSalon GormSalon
+	result := r.db.WithContext(ctx).Where("id = ?", salonID).First(&gormSalon)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			r.log.Debug("SalonRepo_Read: record not found", "id", salonID)
+			return domain.Salon{}, domain.RecordNotFound
+		}
+		r.log.Error("SalonRepo_Read: internal error", "id", salonID, "err", result.Error)
+		return domain.Salon{}, domain.InternalError
+	}
+
+	r.log.Debug("SalonRepo_Read: End!")
+	return toDomainSalon(gormSalon), nil
+}
+
+func (r *salonRepo) List(ctx context.Context, filter domain.SalonFilter) ([]domain.Salon, error) {
+	r.log.Debug("SalonRepo_List: Start!")
+
+	query := r.db.WithContext(ctx).Order("name")
+	if filter.City != "" {
+		query = query.Where("LOWER(city) = LOWER(?)", filter.City)
+	}
+
+	var gormSalons []GormSalon
+	if err := query.Find(&gormSalons).Error; err != nil {
+		r.log.Error("SalonRepo_List: internal error", "err", err)
+		return nil, fmt.Errorf("%w: list salons error: %v", domain.InternalError, err)
+	}
+
+	salons := make([]domain.Salon, 0, len(gormSalons))
+	for _, gormSalon := range gormSalons {
+		salons = append(salons, toDomainSalon(gormSalon))
+	}
+
+	r.log.Debug("SalonRepo_List: End!", "count", len(salons))
+	return salons, nil
+}
@@ -0,0 +1,150 @@
+package salonrepo
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/repotest"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+var (
+	db *gorm.DB
+	integration = repotest.Integration
+	slRepo usecase.SalonRepo
+	lg usecase.Logger
+)
+
+func TestMain(m *testing.M) {
+	repotest.Main(m, func(testDB *gorm.DB) {
+		db = testDB
+		lg = logger.NewLogger()
+		slRepo = NewSalonRepo(db, lg)
+	})
+}
+
+func TestSalonRepo_GetOrCreateKeepsDetails(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+	defer db.Where("name = ?", "Тестовый салон").Delete(&GormSalon{})
+
+	imported, err := slRepo.Upsert(ctx, domain.Salon{
+		Name: "Тестовый салон",
+		City: "Москва",
+		Address: "ул. Тестовая, 1",
+		Phone: "+7 000 000-00-00",
+	})
+	if err != nil {
+		t.Fatalf("upsert salon error: %v", err)
+	}
+
+	parsed, err := slRepo.GetOrCreate(ctx, domain.Salon{Name: "Тестовый салон", URL: "https://example.com/salon"})
+	if err != nil {
+		t.Fatalf("get or create salon error: %v", err)
+	}
+
+	if parsed.ID != imported.ID {
+		t.Errorf("GetOrCreate created a duplicate salon: %d != %d", parsed.ID, imported.ID)
+	}
+	if parsed.Address != imported.Address || parsed.City != "Москва" {
+		t.Errorf("salon details were overwritten: %+v", parsed)
+	}
+	if parsed.URL != "https://example.com/salon" {
+		t.Errorf("empty url was not filled: %q", parsed.URL)
+	}
+
+	salons, err := slRepo.List(ctx, domain.SalonFilter{City: "москва"})
+	if err != nil {
+		t.Fatalf("list salons error: %v", err)
+	}
+	found := false
+	for _, s := range salons {
+		found = found || s.ID == imported.ID
+	}
+	if !found {
+		t.Errorf("salon not found by city")
+	}
+}
+
+/*
+Салоны из объявлений, спарсенных до справочника, заводит миграция 000011.
+Ее имя салона должно совпадать с тем, по которому салон потом ищет обход
+(resolveSalon), иначе обход заведет дубль.
+*/
+func TestSalonRepo_Backfill(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+
+	migration, err := os.ReadFile("../../../../migrations/000011_add_salons.up.sql")
+	if err != nil {
+		t.Fatalf("read migration error: %v", err)
+	}
+	_, backfill, ok := strings.Cut(string(migration), "-- Салоны из уже спарсенных объявлений")
+	if !ok {
+		t.Fatalf("backfill is not found in the migration")
+	}
+
+	// Все пишется в транзакции и откатывается: backfill трогает всю таблицу
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	locations := []string{"  Тестовый  мотосалон ", "Тестовый\u00a0мотосалон", "\tТестовый мотосалон\n", " "}
+	for _, location := range locations {
+		err := tx.Exec(
+			"INSERT INTO motos (source, year, mileage, engine_size, moto_type, location, price, dealer_url) VALUES (?, 2020, 0, 0, '', ?, 0, ?)",
+			"test-salon-backfill", location, "https://example.com/salon",
+		).Error
+		if err != nil {
+			t.Fatalf("insert moto error: %v", err)
+		}
+	}
+
+	for _, statement := range strings.Split(backfill, ";\n") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if err := tx.Exec(statement).Error; err != nil {
+			t.Fatalf("backfill error: %v\n%s", err, statement)
+		}
+	}
+
+	var salons []GormSalon
+	if err := tx.Where("name LIKE ?", "%Тестовый%мотосалон%").Find(&salons).Error; err != nil {
+		t.Fatalf("read backfilled salons error: %v", err)
+	}
+	if len(salons) != 1 || salons[0].Name != "Тестовый мотосалон" {
+		t.Fatalf("unexpected backfilled salons: %+v", salons)
+	}
+
+	var linked int64
+	if err := tx.Table("motos").Where("source = ? AND salon_id = ?", "test-salon-backfill", salons[0].ID).Count(&linked).Error; err != nil {
+		t.Fatalf("count linked motos error: %v", err)
+	}
+	if linked != 3 {
+		t.Errorf("motos linked to the backfilled salon: %d, want 3", linked)
+	}
+
+	// Обход ищет салон по имени, нормализованному strings.Fields, и находит тот же
+	repo := NewSalonRepo(tx, lg)
+	for _, location := range locations[:3] {
+		salon, err := repo.GetOrCreate(ctx, domain.Salon{Name: strings.Join(strings.Fields(location), " ")})
+		if err != nil {
+			t.Fatalf("get or create salon error: %v", err)
+		}
+		if salon.ID != salons[0].ID {
+			t.Errorf("crawl created a duplicate of the backfilled salon for %q: %d != %d", location, salon.ID, salons[0].ID)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package syncrunrepo
+
+import (
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+func toDomainSyncRun(run GormSyncRun) domain.SyncRun {
+	return domain.SyncRun{
+		ID: run.ID,
+		Source: run.Source,
+		Kind: run.Kind,
+		StartedAt: run.StartedAt,
+		FinishedAt: run.FinishedAt,
+		Pages: run.Pages,
+		CardsFound: run.CardsFound,
+		Inserted: run.Inserted,
+		Updated: run.Updated,
+		Deactivated: run.Deactivated,
+		Failed: run.Failed,
+		Error: run.Error,
+	}
+}
+
+func toGormSyncRun(run domain.SyncRun) GormSyncRun {
+	return GormSyncRun{
+		ID: run.ID,
+		Source: run.Source,
+		Kind: run.Kind,
+		StartedAt: run.StartedAt,
+		FinishedAt: run.FinishedAt,
+		Pages: run.Pages,
+		CardsFound: run.CardsFound,
+		Inserted: run.Inserted,
+		Updated: run.Updated,
+		Deactivated: run.Deactivated,
+		Failed: run.Failed,
+		Error: run.Error,
+	}
+}
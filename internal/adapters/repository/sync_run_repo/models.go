@@ -0,0 +1,24 @@
+package syncrunrepo
+
+import (
+	"time"
+)
+
+type GormSyncRun struct {
+	ID uint `gorm:"primaryKey;autoIncrement"`
+	Source string `gorm:"type:varchar(100);index:idx_sync_runs_source_started_at,priority:1"`
+	Kind string `gorm:"type:varchar(16);not null;default:crawl"`
+	StartedAt time.Time `gorm:"not null;index:idx_sync_runs_source_started_at,priority:2"`
+	FinishedAt time.Time `gorm:"not null"`
+	Pages int
+	CardsFound int
+	Inserted int
+	Updated int
+	Deactivated int
+	Failed int
+	Error string `gorm:"type:text"`
+}
+
+func (GormSyncRun) TableName() string {
+	return "sync_runs"
+}
@@ -0,0 +1,71 @@
+package syncrunrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+type syncRunRepo struct {
+	db *gorm.DB
+	log usecase.Logger
+}
+
+func NewSyncRunRepo(db *gorm.DB, log usecase.Logger) usecase.SyncRunRepo {
+	return &syncRunRepo{
+		db: db,
+		log: log,
+	}
+}
+
+func (r *syncRunRepo) Create(ctx context.Context, run domain.SyncRun) (domain.SyncRun, error) {
+	r.log.Debug("SyncRunRepo_Create: Start!", "source", run.Source)
+
+	gormRun := toGormSyncRun(run)
+	if err := r.db.WithContext(ctx).Create(&gormRun).Error; err != nil {
+		r.log.Error("SyncRunRepo_Create: internal error", "source", run.Source, "err", err)
+		return domain.SyncRun{}, fmt.Errorf("%w: create sync run error: %v", domain.InternalError, err)
+	}
+
+	r.log.Debug("SyncRunRepo_Create: End!", "id", gormRun.ID)
+	return toDomainSyncRun(gormRun), nil
+}
+
+func (r *syncRunRepo) List(ctx context.Context, filter domain.SyncRunFilter) ([]domain.SyncRun, error) {
+	r.log.Debug("SyncRunRepo_List: Start!", "source", filter.Source, "status", filter.Status, "kind", filter.Kind)
+
+	query := r.db.WithContext(ctx).Order("started_at DESC, id DESC")
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	switch filter.Status {
+	case domain.SyncRunStatusOK:
+		query = query.Where("error = ''")
+	case domain.SyncRunStatusFailed:
+		query = query.Where("error <> ''")
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var gormRuns []GormSyncRun
+	if err := query.Find(&gormRuns).Error; err != nil {
+		r.log.Error("SyncRunRepo_List: internal error", "err", err)
+		return nil, fmt.Errorf("%w: list sync runs error: %v", domain.InternalError, err)
+	}
+
+	runs := make([]domain.SyncRun, 0, len(gormRuns))
+	for _, gormRun := range gormRuns {
+		runs = append(runs, toDomainSyncRun(gormRun))
+	}
+
+	r.log.Debug("SyncRunRepo_List: End!", "count", len(runs))
+	return runs, nil
+}
@@ -0,0 +1,76 @@
+package syncrunrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/repotest"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+
+	"gorm.io/gorm"
+)
+
+var (
+	db *gorm.DB
+	integration = repotest.Integration
+	srRepo usecase.SyncRunRepo
+	lg usecase.Logger
+)
+
+func TestMain(m *testing.M) {
+	repotest.Main(m, func(testDB *gorm.DB) {
+		db = testDB
+		lg = logger.NewLogger()
+		srRepo = NewSyncRunRepo(db, lg)
+	})
+}
+
+func TestSyncRunRepo_List(t *testing.T) {
+	if !*integration {
+		t.Skip("integration tests disabled")
+	}
+
+	ctx := context.Background()
+	source := "test-sync-runs"
+	defer db.Where("source = ?", source).Delete(&GormSyncRun{})
+
+	start := time.Now().Add(-time.Hour)
+	kinds := []string{domain.SyncRunKindCrawl, domain.SyncRunKindCrawl, domain.SyncRunKindCrawl, domain.SyncRunKindSnapshot}
+	for i, runErr := range []string{"", "timeout", "", ""} {
+		startedAt := start.Add(time.Duration(i) * time.Minute)
+		_, err := srRepo.Create(ctx, domain.SyncRun{
+			Source: source,
+			Kind: kinds[i],
+			StartedAt: startedAt,
+			FinishedAt: startedAt.Add(30 * time.Second),
+			Pages: 10,
+			Inserted: i,
+			Error: runErr,
+		})
+		if err != nil {
+			t.Fatalf("create sync run error: %v", err)
+		}
+	}
+
+	runs, err := srRepo.List(ctx, domain.SyncRunFilter{Source: source, Limit: 10})
+	if err != nil {
+		t.Fatalf("list sync runs error: %v", err)
+	}
+	if len(runs) != 4 || runs[0].Inserted != 3 || runs[0].Kind != domain.SyncRunKindSnapshot {
+		t.Errorf("runs are not newest first: %+v", runs)
+	}
+
+	// Свежесть данных - по последнему удачному обходу сайта, не снимка
+	latest, err := srRepo.List(ctx, domain.SyncRunFilter{Source: source, Status: domain.SyncRunStatusOK, Kind: domain.SyncRunKindCrawl, Limit: 1})
+	if err != nil || len(latest) != 1 || !latest[0].Succeeded() || latest[0].Inserted != 2 {
+		t.Errorf("unexpected latest successful crawl: %+v, err: %v", latest, err)
+	}
+
+	failed, err := srRepo.List(ctx, domain.SyncRunFilter{Source: source, Status: domain.SyncRunStatusFailed})
+	if err != nil || len(failed) != 1 || failed[0].Error != "timeout" {
+		t.Errorf("unexpected failed runs: %+v, err: %v", failed, err)
+	}
+}
@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule - расписание в формате cron из пяти полей:
+// минута, час, день месяца, месяц, день недели (0 и 7 - воскресенье).
+// Поддерживаются "*", числа, диапазоны "a-b", шаг "*/n" и "a-b/n",
+// списки через запятую и сокращения @hourly, @daily, @weekly, @monthly.
+// Как и в обычном cron, если заданы и день месяца, и день недели,
+// подходит любой из них.
+type CronSchedule struct {
+	expr string
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny bool
+}
+
+var cronDescriptors = map[string]string{
+	"@hourly": "0 * * * *",
+	"@daily": "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly": "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+func ParseCron(expr string) (CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	schedule := CronSchedule{expr: expr}
+
+	spec := expr
+	if full, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		spec = full
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return schedule, fmt.Errorf("%w: cron %q: expected 5 fields, got %d", InvalidInput, expr, len(fields))
+	}
+
+	var err error
+	if schedule.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return schedule, fmt.Errorf("%w: cron %q: minute: %v", InvalidInput, expr, err)
+	}
+	if schedule.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return schedule, fmt.Errorf("%w: cron %q: hour: %v", InvalidInput, expr, err)
+	}
+	if schedule.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return schedule, fmt.Errorf("%w: cron %q: day of month: %v", InvalidInput, expr, err)
+	}
+	if schedule.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return schedule, fmt.Errorf("%w: cron %q: month: %v", InvalidInput, expr, err)
+	}
+	if schedule.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return schedule, fmt.Errorf("%w: cron %q: day of week: %v", InvalidInput, expr, err)
+	}
+
+	// 7 - то же воскресенье, что и 0
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	schedule.domAny = strings.HasPrefix(fields[2], "*")
+	schedule.dowAny = strings.HasPrefix(fields[4], "*")
+
+	return schedule, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		stepped := false
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step, stepped = part[:i], n, true
+		}
+
+		lo, hi := min, max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			bounds := strings.SplitN(rng, "-", 2)
+			a, errA := strconv.Atoi(bounds[0])
+			b, errB := strconv.Atoi(bounds[1])
+			if errA != nil || errB != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			// "5/15" - с 5 до конца с шагом 15
+			lo = n
+			if !stepped {
+				hi = n
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (c CronSchedule) String() string {
+	return c.expr
+}
+
+// Next возвращает первый момент расписания строго после after,
+// или нулевое время, если за ближайшие пять лет такого нет ("0 0 30 2 *").
+func (c CronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Add(time.Minute - time.Duration(after.Second())*time.Second - time.Duration(after.Nanosecond()))
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c CronSchedule) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Состояние источника по последнему обходу
+const (
+	HealthUnknown = "unknown" // обходов еще не было
+	HealthOK = "ok"
+	HealthDegraded = "degraded" // найден дрейф разметки, но данные записаны
+	HealthFailed = "failed" // обход упал или данные не записаны из-за дрейфа
+)
+
+// Что делать с обходом, в котором найден дрейф
+const (
+	DriftActionRefuse = "refuse" // не записывать данные в базу
+	DriftActionFlag = "flag" // записать, но пометить источник как degraded
+)
+
+// Проверки дрейфа
+const (
+	DriftCheckCards = "cards"
+	DriftCheckRejected = "rejected"
+	DriftCheckEmptyField = "empty_field"
+	DriftCheckUnknownLabels = "unknown_labels"
+)
+
+/*
+DriftPolicy - пороги, по которым обход считается подозрительным.
+Если сайт поменял разметку, парсер обычно не падает, а возвращает
+ноль карточек или карточки с пустыми полями - такой обход нельзя
+записывать поверх нормальных данных.
+*/
+type DriftPolicy struct {
+	Action string
+	HistorySize int // со сколькими прошлыми удачными обходами сравнивать
+
+	MinCardsRatio float64 // карточек меньше этой доли от среднего по прошлым обходам
+	MaxRejectedShare float64 // доля отбракованных карточек, без оглядки на историю
+	MaxEmptyShareIncrease float64 // рост доли пустого поля по сравнению с прошлыми обходами
+	MaxUnknownLabelIncrease float64 // рост доли незнакомых подписей по сравнению с прошлыми обходами
+}
+
+func DefaultDriftPolicy() DriftPolicy {
+	return DriftPolicy{
+		Action: DriftActionRefuse,
+		HistorySize: 5,
+		MinCardsRatio: 0.5,
+		MaxRejectedShare: 0.5,
+		MaxEmptyShareIncrease: 0.3,
+		MaxUnknownLabelIncrease: 0.2,
+	}
+}
+
+type DriftIssue struct {
+	Check string
+	Field string // для DriftCheckEmptyField
+	Value float64
+	Baseline float64 // среднее по прошлым обходам
+	Message string
+}
+
+// SourceHealth - здоровье источника по последнему обходу.
+type SourceHealth struct {
+	Source string
+	Status string
+	CheckedAt time.Time
+	Committed bool // записаны ли данные последнего обхода
+	CardsFound int
+	Issues []DriftIssue
+	Error string
+}
+
+/*
+DetectDrift сравнивает отчет обхода с прошлыми удачными обходами источника.
+Без истории проверяется только то, что не зависит от нее: ноль карточек,
+массовая отбраковка и незнакомые подписи.
+*/
+func DetectDrift(report ParseReport, history []ParseReport, policy DriftPolicy) []DriftIssue {
+	var issues []DriftIssue
+
+	avgCards := average(history, func(r ParseReport) float64 { return float64(r.CardsFound) })
+	switch {
+	case report.CardsFound == 0:
+		issues = append(issues, DriftIssue{
+			Check: DriftCheckCards,
+			Baseline: avgCards,
+			Message: "no cards found",
+		})
+	case avgCards > 0 && float64(report.CardsFound) < policy.MinCardsRatio*avgCards:
+		issues = append(issues, DriftIssue{
+			Check: DriftCheckCards,
+			Value: float64(report.CardsFound),
+			Baseline: avgCards,
+			Message: fmt.Sprintf("found %d cards, previous crawls averaged %.0f", report.CardsFound, avgCards),
+		})
+	}
+
+	return append(issues, detectQualityDrift(report, history, policy)...)
+}
+
+/*
+DetectFirstPageDrift - те же проверки по отчету первой страницы каталога,
+кроме числа карточек: его до конца обхода не узнать. Так "уплывшую" разметку
+видно раньше, чем что-то запишется в базу.
+*/
+func DetectFirstPageDrift(report ParseReport, history []ParseReport, policy DriftPolicy) []DriftIssue {
+	return detectQualityDrift(report, history, policy)
+}
+
+// detectQualityDrift - проверки, которые считаются долями и не зависят от размера обхода.
+func detectQualityDrift(report ParseReport, history []ParseReport, policy DriftPolicy) []DriftIssue {
+	var issues []DriftIssue
+
+	if rejected := share(report.CardsRejected, report.CardsFound); rejected > policy.MaxRejectedShare {
+		issues = append(issues, DriftIssue{
+			Check: DriftCheckRejected,
+			Value: rejected,
+			Message: fmt.Sprintf("%.0f%% of cards rejected", rejected*100),
+		})
+	}
+
+	// Доля пустых полей сама по себе ничего не значит (у новых мотоциклов нет пробега),
+	// поэтому сравниваем только с историей
+	if len(history) > 0 && report.CardsParsed > 0 {
+		for _, field := range emptyFieldNames(report, history) {
+			value := share(report.EmptyFields[field], report.CardsParsed)
+			baseline := average(history, func(r ParseReport) float64 {
+				return share(r.EmptyFields[field], r.CardsParsed)
+			})
+			if value-baseline > policy.MaxEmptyShareIncrease {
+				issues = append(issues, DriftIssue{
+					Check: DriftCheckEmptyField,
+					Field: field,
+					Value: value,
+					Baseline: baseline,
+					Message: fmt.Sprintf("field %s is empty in %.0f%% of motos, previously %.0f%%", field, value*100, baseline*100),
+				})
+			}
+		}
+	}
+
+	unknown := unknownLabelShare(report)
+	baseline := average(history, unknownLabelShare)
+	if unknown-baseline > policy.MaxUnknownLabelIncrease {
+		issues = append(issues, DriftIssue{
+			Check: DriftCheckUnknownLabels,
+			Value: unknown,
+			Baseline: baseline,
+			Message: fmt.Sprintf("%.0f%% of labels are unknown, previously %.0f%%", unknown*100, baseline*100),
+		})
+	}
+
+	return issues
+}
+
+func unknownLabelShare(r ParseReport) float64 {
+	var unknown int
+	for _, n := range r.UnknownLabels {
+		unknown += n
+	}
+	return share(unknown, r.LabelsSeen)
+}
+
+func emptyFieldNames(report ParseReport, history []ParseReport) []string {
+	seen := make(map[string]bool)
+	for field := range report.EmptyFields {
+		seen[field] = true
+	}
+	for _, r := range history {
+		for field := range r.EmptyFields {
+			seen[field] = true
+		}
+	}
+
+	fields := make([]string, 0, len(seen))
+	for field := range seen {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func average(reports []ParseReport, value func(ParseReport) float64) float64 {
+	if len(reports) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, r := range reports {
+		sum += value(r)
+	}
+	return sum / float64(len(reports))
+}
@@ -1,36 +1,299 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Moto struct {
 	ID uint
-	Name string
+	ExternalID string // стабильный ключ объявления у источника
+	Source string // имя источника, с которого спарсен мотоцикл
+	Name string // заголовок объявления как на сайте
+	Brand string // каноничная марка из словаря: "Yamaha", "Harley-Davidson"
+	Model string
+	Trim string // комплектация/модификация, все что в заголовке после модели
 	Year int
-	Mileage int
-	EngineSize int
-	MotoType string
-	Location string
-	Price int64
+	Mileage int // км; 0, если пробег указан в моточасах
+	MileageOriginal int // пробег как на сайте, в единицах MileageUnit
+	MileageUnit string // MileageUnitKm, MileageUnitMiles или MileageUnitHours
+	EngineSize int // cc; у электромотоциклов 0
+	EngineSizeUnit string // в чем объем был указан на сайте: EngineSizeUnitCC, EngineSizeUnitLitre, EngineSizeUnitKW
+	Powertrain string // PowertrainICE, PowertrainElectric или пусто, если неизвестно
+	MotorPowerKW float64 // мощность электромотора
+	MotoType string // класс как на сайте
+	Class MotoClass // каноничный класс, пусто - не удалось сопоставить
+	Location string // название салона как на сайте
+	SalonID *uint // салон, см. Salon; nil, если салон не указан
+	Price int64 // текущая цена, 0 если цены нет (см. PriceStatus)
+	OldPrice int64 // зачеркнутая цена до скидки, 0 если скидки нет
+	PriceCurrency string // ISO-код валюты, обычно PriceCurrencyRUB
+	PriceStatus string // PriceStatusFixed, PriceStatusFrom, PriceStatusOnRequest или PriceStatusUnknown
+	PreviousPrice int64 // цена до последнего изменения при обходе, 0 - не менялась; история в PricePoint
+	PriceChangedAt *time.Time
+	RecentPriceDrop bool // цена снижалась за RecentPriceDropWindow, считается при выдаче, в базе не хранится
+	SourceURL string // ссылка на объявление у источника
+	ImageURL string
+	DealerURL string
+
+	// Поля со страницы объявления. Заполняются только при обходе с деталями.
+	Horsepower int
+	Weight int // кг
+	Color string
+	Transmission string
+	ABS *bool
+	Description string
+	DetailsParsedAt *time.Time
+
+	// Когда объявление последний раз было в каталоге источника. Если полный
+	// обход его не нашел, оно снимается с показа (DeactivatedAt) и
+	// возвращается, если снова появится.
+	LastSeenAt *time.Time
+	DeactivatedAt *time.Time // nil - объявление активно
+
 	CreatedAt *time.Time
 	UpdatedAt *time.Time
 }
 
+func (m Moto) IsActive() bool {
+	return m.DeactivatedAt == nil
+}
+
+// Состояния цены объявления
+const (
+	PriceStatusFixed = "fixed" // обычная цена
+	PriceStatusFrom = "from" // "от 1 190 000 р." - нижняя граница цены
+	PriceStatusOnRequest = "on_request" // "Цена по запросу", числа нет
+	PriceStatusUnknown = "unknown" // цену не удалось найти или разобрать
+)
+
+const PriceCurrencyRUB = "RUB"
+
+// Единицы пробега
+const (
+	MileageUnitKm = "km"
+	MileageUnitMiles = "mi"
+	MileageUnitHours = "h" // моточасы, в км не переводятся
+)
+
+// Единицы, в которых на сайте указан двигатель
+const (
+	EngineSizeUnitCC = "cc"
+	EngineSizeUnitLitre = "l"
+	EngineSizeUnitKW = "kw" // электромотор, вместо объема мощность
+)
+
+// Тип силовой установки
+const (
+	PowertrainICE = "ice" // двигатель внутреннего сгорания
+	PowertrainElectric = "electric"
+)
+
+// HasPrice - есть ли у объявления цена, по которой его можно сравнивать с бюджетом.
+func (m Moto) HasPrice() bool {
+	return m.Price > 0 && (m.PriceStatus == PriceStatusFixed || m.PriceStatus == PriceStatusFrom)
+}
+
+/*
+ListingKey возвращает стабильный ключ объявления.
+Если известна ссылка на объявление, ключом служит она. Иначе считается
+sha256 от полей, которые не меняются у одного и того же мотоцикла между
+обходами каталога. Цена и пробег сюда не входят: цену меняют, а пробег
+дилер уточняет, и объявление не должно от этого стать новым.
+Сохраненные ключи по этой формуле пересчитывает MotoRepo.RekeyListings
+(cmd/rekey) - если формула меняется, его нужно запустить снова.
+*/
+func (m Moto) ListingKey() string {
+	if m.SourceURL != "" {
+		return m.SourceURL
+	}
+
+	parts := []string{
+		m.Source,
+		strings.ToLower(strings.TrimSpace(m.Name)),
+		strconv.Itoa(m.Year),
+		strconv.Itoa(m.EngineSize),
+		strings.ToLower(strings.TrimSpace(m.MotoType)),
+		strings.ToLower(strings.TrimSpace(m.Location)),
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseOptions - настройки одного запуска парсинга.
+type ParseOptions struct {
+	Source string // пустая строка - все включенные источники
+	WithDetails bool // заходить на страницу каждого объявления за доп. характеристиками
+	Snapshot string // id снимка сырых страниц: разобрать его вместо обхода сайта
+	// Записать разобранный снимок в базу. Без него разбор снимка только
+	// считает отчет и дрейф, ничего не меняя
+	Commit bool
+
+	// Загруженный файл для файлового источника, разбирается вместо файла из конфига.
+	// Только вместе с Source.
+	File []byte
+	FileName string
+
+	// Записать данные, даже если найден дрейф разметки, и принять обход за новую норму
+	Force bool
+
+	// Вызывается по ходу обхода, в том числе из разных горутин; nil - не нужно
+	Progress func(ParseProgress)
+
+	// Вызывается один раз, когда разобрана первая страница каталога, с отчетом
+	// на этот момент - до того, как ее мотоциклы уйдут дальше. Ошибка останавливает обход
+	FirstPage func(ParseReport) error
+}
+
+func (o ParseOptions) NotifyProgress(p ParseProgress) {
+	if o.Progress != nil {
+		o.Progress(p)
+	}
+}
+
+func (o ParseOptions) CheckFirstPage(r ParseReport) error {
+	if o.FirstPage == nil {
+		return nil
+	}
+	return o.FirstPage(r)
+}
+
+// DryRun - разбор снимка без записи в базу, см. Commit.
+func (o ParseOptions) DryRun() bool {
+	return o.Snapshot != "" && !o.Commit
+}
+
+// SyncRunKind - вид обхода для журнала, см. SyncRunKindCrawl.
+func (o ParseOptions) SyncRunKind() string {
+	switch {
+	case o.File != nil:
+		return SyncRunKindFile
+	case o.Snapshot != "":
+		return SyncRunKindSnapshot
+	default:
+		return SyncRunKindCrawl
+	}
+}
+
+// UpsertOptions - настройки записи мотоциклов этого запуска в базу.
+func (o ParseOptions) UpsertOptions() UpsertOptions {
+	return UpsertOptions{
+		// В старом снимке страниц и в файле дилера цены не свежие
+		RecordPriceHistory: o.Snapshot == "" && o.File == nil,
+		// Старый снимок не говорит о том, что объявление есть в каталоге сейчас
+		MarkSeen: o.Snapshot == "",
+	}
+}
+
+// UpsertOptions - что делает запись пачки мотоциклов помимо самих полей.
+type UpsertOptions struct {
+	// Вести историю цен: moto_price_history, previous_price и price_changed_at
+	RecordPriceHistory bool
+	// Обновить last_seen_at и вернуть снятое объявление в показ
+	MarkSeen bool
+}
+
+/*
+ParseReport - диагностика одного обхода источника.
+По нему видно, что разметка сайта "уплыла": карточки отбраковываются,
+появляются незнакомые подписи или поля массово приходят пустыми.
+*/
+type ParseReport struct {
+	Source string
+	StartedAt time.Time
+	FinishedAt time.Time
+	Snapshot string // id записанного или проигранного снимка страниц
+
+	PagesFetched int
+	PaginationMode string // PaginationCumulative или PaginationPaged
+	Truncated bool // обход остановился на max_page_count, в каталоге могли остаться страницы
+	CardsFound int
+	CardsParsed int
+	CardsRejected int
+	RejectedCards []RejectedCard // первые MaxRejectedCardsInReport штук
+
+	DetailPagesFetched int
+	DetailPagesFailed int
+
+	LabelsSeen int // сколько всего подписей характеристик встретилось
+	UnknownLabels map[string]int // подпись -> сколько раз встретилась
+	UnknownMotoTypes map[string]int // класс с сайта, которого нет в таблице синонимов
+	EmptyFields map[string]int // поле -> у скольких мотоциклов оно пустое
+
+	Drift []DriftIssue // см. DetectDrift
+
+	// Итог записи в базу
+	Inserted int
+	Updated int
+	Failed int
+	Deactivated int // сколько объявлений пропало из каталога после этого обхода
+	Error string
+}
+
+// Режимы пагинации каталога
+const (
+	PaginationCumulative = "cumulative" // страница N содержит и все карточки предыдущих
+	PaginationPaged = "paged" // страница N содержит только свои карточки
+)
+
+// Сколько отбракованных карточек хранить в отчете поименно
+const MaxRejectedCardsInReport = 100
+
+type RejectedCard struct {
+	PageURL string
+	Index int
+	Reason string
+}
+
+// ParseResult - итог запуска парсинга всех выбранных источников.
+type ParseResult struct {
+	Upserted int
+	Failed int
+	Deactivated int
+	Reports []ParseReport
+}
+
 type MotoFilter struct {
 	EngineSizeMin *int   // cc
 	EngineSizeMax *int   // cc
 
+	// Если задана хоть одна граница цены, объявления без цены
+	// (по запросу, не разобрана) в выдачу не попадают
 	PriceMin      *int64 // если понадобится
 	PriceMax      *int64
+	// Валюта границ цены, пусто - PriceCurrencyRUB. Цены в другой
+	// валюте с границами не сравнить, такие объявления не попадают
+	PriceCurrency string
 
 	YearMin       *int
 	YearMax       *int
 
+	// Пробег в моточасах с км не сравнить, такие мотоциклы
+	// при заданном диапазоне пробега в выдачу не попадают
 	MileageMin    *int   // км
 	MileageMax    *int   // км
 
-	MotoType      string
+	Class         MotoClass // пусто - любой
+
+	// Марка и модель сравниваются без учета регистра, пусто - любая
+	Brand         string
+	Model         string
+
+	SalonID       *uint
+	City          string // город салона, без учета регистра
+
+	// При заданном диапазоне объема подходят только мотоциклы с ДВС
+	Powertrain    string // пусто - любой
+
+	// Снятые с продажи объявления по умолчанию не показываются
+	IncludeInactive bool
+
+	// Только объявления, подешевевшие за RecentPriceDropWindow
+	PriceDropped bool
 }
 
 func NewMotoFilter(
@@ -38,7 +301,7 @@ func NewMotoFilter(
 	yearOption int,
 	mileageOption int,
 	priceMaxOption *int64,
-	motoType string,
+	class MotoClass,
 ) MotoFilter {
 	engineMin, engineMax := engineSizeRange(engineSizeOption)
 	yearMin, yearMax := yearRange(yearOption)
@@ -54,7 +317,7 @@ func NewMotoFilter(
 		MileageMax:    mileageMax,
 		PriceMin:      priceMin,
 		PriceMax:      priceMax,
-		MotoType:      motoType,
+		Class:         class,
 	}
 }
 
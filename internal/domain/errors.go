@@ -9,4 +9,10 @@ var (
 	InternalError = errors.New("internal error")
 	RecordNotFound = errors.New("record not found")
 	RecordAlreadyExists = errors.New("record already exists")
+	SourceNotFound = errors.New("source not found")
+	SnapshotNotFound = errors.New("snapshot not found")
+	InvalidInput = errors.New("invalid input")
+	SchemaDrift = errors.New("schema drift")
+	SyncAlreadyRunning = errors.New("sync already running")
+	JobFinished = errors.New("job already finished")
 )
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"time"
+)
+
+// Состояния задачи парсинга
+const (
+	JobStatusRunning = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed = "failed"
+	JobStatusCanceled = "canceled"
+	JobStatusInterrupted = "interrupted" // экземпляр приложения остановился посреди обхода
+)
+
+/*
+ParseJob - запуск ParseAndUpdateAllMoto в фоне. Хранится в базе,
+чтобы статус можно было посмотреть и после перезапуска приложения.
+Счетчики обновляются по ходу обхода, см. ParseProgress.
+*/
+type ParseJob struct {
+	ID string
+	Status string
+	Owner string // id экземпляра приложения, который выполняет задачу
+	CancelRequested bool // отмену попросили через другой экземпляр, владелец ее подхватит
+
+	// Параметры запуска, как в ParseOptions
+	Source string
+	WithDetails bool
+	Snapshot string
+	Force bool
+
+	PagesFetched int
+	Upserted int
+	Failed int
+	Deactivated int
+	Errors []string
+
+	CreatedAt time.Time
+	StartedAt *time.Time
+	FinishedAt *time.Time
+}
+
+func (j ParseJob) Finished() bool {
+	return j.Status != JobStatusRunning
+}
+
+// ParseProgress - прибавка к ходу обхода, см. ParseOptions.Progress.
+type ParseProgress struct {
+	Pages int // скачано страниц каталога
+	Upserted int
+	Failed int
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"strings"
+)
+
+/*
+MotoClass - каноничный класс мотоцикла.
+Сайты пишут класс как угодно ("Круизер", "Чоппер", "Кастом"), парсер
+приводит их к этому списку по таблице синонимов, а подбор на фронте
+и фильтр работают только с ним.
+*/
+type MotoClass string
+
+const (
+	MotoClassSport MotoClass = "sport"
+	MotoClassSportTouring MotoClass = "sport_touring"
+	MotoClassTouring MotoClass = "touring"
+	MotoClassNaked MotoClass = "naked"
+	MotoClassClassic MotoClass = "classic"
+	MotoClassChopper MotoClass = "chopper"
+	MotoClassEnduro MotoClass = "enduro"
+	MotoClassAdventure MotoClass = "adventure"
+	MotoClassMotocross MotoClass = "motocross"
+	MotoClassSupermoto MotoClass = "supermoto"
+	MotoClassScooter MotoClass = "scooter"
+)
+
+type MotoClassInfo struct {
+	Class MotoClass
+	Title string
+	Description string
+}
+
+// Порядок списка - порядок карточек в подборе
+var motoClasses = []MotoClassInfo{
+	{MotoClassSportTouring, "Спорт-турист", "Для скорости и отдыха"},
+	{MotoClassClassic, "Классик", "Подходит для большинства"},
+	{MotoClassEnduro, "Эндуро", "Для бездорожья"},
+	{MotoClassSport, "Спорт", "Для быстрых поездок"},
+	{MotoClassChopper, "Чоппер", "Если хочется раздать стиля"},
+	{MotoClassNaked, "Нейкед", "Для города и загорода"},
+	{MotoClassTouring, "Турер", "Для дальних поездок с комфортом"},
+	{MotoClassAdventure, "Туристический эндуро", "Для путешествий по любым дорогам"},
+	{MotoClassMotocross, "Кросс", "Для трассы и соревнований"},
+	{MotoClassSupermoto, "Супермото", "Для города и картинга"},
+	{MotoClassScooter, "Скутер", "Для коротких поездок по городу"},
+}
+
+func MotoClasses() []MotoClassInfo {
+	return append([]MotoClassInfo(nil), motoClasses...)
+}
+
+/*
+ParseMotoClass принимает id класса ("chopper") или его название ("Чоппер").
+Пустая строка и "-" - любой класс, тогда возвращается "" и true.
+*/
+func ParseMotoClass(s string) (MotoClass, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		return "", true
+	}
+
+	for _, info := range motoClasses {
+		if string(info.Class) == s || strings.EqualFold(info.Title, s) {
+			return info.Class, true
+		}
+	}
+
+	return "", false
+}
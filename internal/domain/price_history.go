@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"time"
+)
+
+// Сколько времени снижение цены считается свежим
+const RecentPriceDropWindow = 14 * 24 * time.Hour
+
+/*
+PricePoint - цена объявления на момент обхода. Запись появляется,
+когда объявление впервые попало в базу и когда обход изменил его цену.
+*/
+type PricePoint struct {
+	MotoID uint
+	Price int64
+	OldPrice int64
+	PriceCurrency string
+	PriceStatus string
+	RecordedAt time.Time
+}
+
+// PriceDroppedWithin - снижал ли дилер цену за последние window.
+func (m Moto) PriceDroppedWithin(window time.Duration, now time.Time) bool {
+	if m.PriceChangedAt == nil || !m.HasPrice() {
+		return false
+	}
+	return m.PreviousPrice > m.Price && now.Sub(*m.PriceChangedAt) <= window
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"time"
+)
+
+// Salon - мотосалон, в котором выставлен мотоцикл.
+type Salon struct {
+	ID uint
+	Name string // как на сайте источника, по нему объявления связываются с салоном
+	City string
+	Address string
+	Latitude *float64
+	Longitude *float64
+	Phone string
+	URL string // страница салона у источника
+
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+type SalonFilter struct {
+	City string // без учета регистра, пусто - любой
+}
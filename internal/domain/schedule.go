@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"time"
+)
+
+/*
+SchedulePolicy - настройки обхода каталога по расписанию.
+После неудачного обхода следующий запускается раньше расписания:
+через BackoffMin, затем вдвое дольше после каждой новой неудачи,
+но не дольше BackoffMax и не позже очередного запуска по расписанию.
+*/
+type SchedulePolicy struct {
+	Cron string // см. CronSchedule
+	Jitter time.Duration // к каждому запуску добавляется случайная задержка до Jitter
+	BackoffMin time.Duration // 0 - не повторять неудачный обход раньше расписания
+	BackoffMax time.Duration
+}
+
+func DefaultSchedulePolicy() SchedulePolicy {
+	return SchedulePolicy{
+		Cron: "0 */6 * * *",
+		Jitter: 10 * time.Minute,
+		BackoffMin: time.Minute,
+		BackoffMax: time.Hour,
+	}
+}
+
+// Backoff - задержка повтора после failures неудач подряд.
+func (p SchedulePolicy) Backoff(failures int) time.Duration {
+	if failures <= 0 || p.BackoffMin <= 0 {
+		return 0
+	}
+
+	delay := p.BackoffMin
+	for i := 1; i < failures && delay < p.BackoffMax; i++ {
+		delay *= 2
+	}
+	if p.BackoffMax > 0 && delay > p.BackoffMax {
+		delay = p.BackoffMax
+	}
+	return delay
+}
+
+// Чем был вызван обход планировщика
+const (
+	SyncTriggerSchedule = "schedule"
+	SyncTriggerRetry = "retry" // повтор после неудачи
+	SyncTriggerManual = "manual" // "запустить сейчас" через API
+)
+
+// SchedulerStatus - состояние планировщика и итог его последнего обхода.
+type SchedulerStatus struct {
+	Enabled bool
+	Running bool
+	Cron string
+	Jitter string
+	NextRunAt *time.Time // nil, если планировщик выключен
+	NextTrigger string
+
+	LastTrigger string
+	LastStartedAt *time.Time
+	LastFinishedAt *time.Time
+	LastUpserted int
+	LastFailed int
+	LastDeactivated int
+	LastError string
+	ConsecutiveFailures int
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"time"
+)
+
+/*
+SyncRun - запись журнала обходов: один обход одного источника, удачный
+или нет. По журналу видно, насколько свежие данные и какие обходы падают.
+*/
+type SyncRun struct {
+	ID uint
+	Source string
+	Kind string // SyncRunKindCrawl, SyncRunKindSnapshot или SyncRunKindFile
+	StartedAt time.Time
+	FinishedAt time.Time
+
+	Pages int
+	CardsFound int
+	Inserted int // новые объявления
+	Updated int // уже известные объявления, перезаписанные обходом
+	Deactivated int
+	Failed int // не записались в базу
+
+	Error string // пусто - обход удачный
+}
+
+func (r SyncRun) Succeeded() bool {
+	return r.Error == ""
+}
+
+// Сколько записей журнала отдавать по умолчанию и максимум
+const (
+	DefaultSyncRunsLimit = 50
+	MaxSyncRunsLimit = 500
+)
+
+// Отбор записей журнала по статусу
+const (
+	SyncRunStatusOK = "ok"
+	SyncRunStatusFailed = "failed"
+)
+
+// Откуда обход взял данные. Свежесть каталога показывает только SyncRunKindCrawl:
+// снимок и файл дилера могут быть сколько угодно старыми
+const (
+	SyncRunKindCrawl = "crawl" // живой обход сайта
+	SyncRunKindSnapshot = "snapshot" // разбор сохраненного снимка страниц
+	SyncRunKindFile = "file" // загруженный файл
+)
+
+type SyncRunFilter struct {
+	Source string // пусто - все источники
+	Status string // SyncRunStatusOK, SyncRunStatusFailed или пусто - все
+	Kind string // SyncRunKind* или пусто - все
+	Limit int // новые записи первыми
+}
+
+// UpsertStats - итог записи пачки мотоциклов.
+type UpsertStats struct {
+	Inserted int
+	Updated int
+}
+
+func (s UpsertStats) Total() int {
+	return s.Inserted + s.Updated
+}
+
+// RekeyStats - итог пересчета ключей объявлений.
+type RekeyStats struct {
+	Rekeyed int // ключ пересчитан
+	Merged int // строки-дубли, слитые с более свежей под тем же ключом
+}
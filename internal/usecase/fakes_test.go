@@ -0,0 +1,424 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+/*
+Фейки портов для тестов сервисов: хранилища в памяти и сервисы,
+которые отдают обход под управление теста.
+*/
+
+// eventually опрашивает get, пока ok не вернет true, и отдает последнее значение.
+// Не дождались за timeout - тест падает.
+func eventually[T any](t *testing.T, timeout time.Duration, get func() T, ok func(T) bool) T {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		v := get()
+		if ok(v) {
+			return v
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not reached in %s: %+v", timeout, v)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// fakeParser отдает заданное число карточек, у части из них нет цены.
+type fakeParser struct {
+	cards int
+	emptyPrice int
+	truncated bool // обход уперся в max_page_count
+	locations []string // салоны карточек по кругу
+}
+
+func (p *fakeParser) GetAllMoto(ctx context.Context, opts domain.ParseOptions) ([]domain.Moto, domain.ParseReport, error) {
+	return nil, domain.ParseReport{}, errors.New("not implemented")
+}
+
+func (p *fakeParser) StreamAllMoto(ctx context.Context, opts domain.ParseOptions, out chan<- domain.Moto) (domain.ParseReport, error) {
+	report := domain.ParseReport{
+		CardsFound: p.cards,
+		CardsParsed: p.cards,
+		EmptyFields: map[string]int{"price": p.emptyPrice},
+		Truncated: p.truncated,
+	}
+	// Весь каталог у фейка - одна страница
+	if err := opts.CheckFirstPage(report); err != nil {
+		return report, err
+	}
+	for i := 0; i < p.cards; i++ {
+		m := domain.Moto{SourceURL: fmt.Sprintf("https://catalog.test/%d", i)}
+		if len(p.locations) > 0 {
+			m.Location = p.locations[i%len(p.locations)]
+		}
+		out <- m
+	}
+	return report, nil
+}
+
+// Снимок у фейка есть любой, кроме "missing"
+func (p *fakeParser) CheckSnapshot(id string) error {
+	if id == "missing" {
+		return domain.SnapshotNotFound
+	}
+	return nil
+}
+
+// fakeMotoRepo считает записанные и снятые с показа мотоциклы, остальные методы не нужны.
+type fakeMotoRepo struct {
+	usecase.MotoRepo
+	upserted int
+	reconciled int // сколько раз вызывался DeactivateMissing
+	broken string // SourceURL строки, на которой падает запись пачки
+	motos []domain.Moto // записанные; их же отдает GetMotosByFilter
+}
+
+// GetMotosByFilter у фейка фильтрует только по салону.
+func (r *fakeMotoRepo) GetMotosByFilter(ctx context.Context, filter domain.MotoFilter) ([]domain.Moto, error) {
+	var motos []domain.Moto
+	for _, m := range r.motos {
+		if filter.SalonID == nil || (m.SalonID != nil && *m.SalonID == *filter.SalonID) {
+			motos = append(motos, m)
+		}
+	}
+	return motos, nil
+}
+
+func (r *fakeMotoRepo) UpsertMany(ctx context.Context, motos []domain.Moto, opts domain.UpsertOptions) (domain.UpsertStats, error) {
+	for _, m := range motos {
+		if opts.MarkSeen != (m.LastSeenAt != nil) {
+			return domain.UpsertStats{}, fmt.Errorf("moto last seen at %v, want marked as seen: %v", m.LastSeenAt, opts.MarkSeen)
+		}
+		if r.broken != "" && m.SourceURL == r.broken {
+			return domain.UpsertStats{}, fmt.Errorf("broken moto %s", m.SourceURL)
+		}
+	}
+	r.upserted += len(motos)
+	r.motos = append(r.motos, motos...)
+	return domain.UpsertStats{Inserted: len(motos)}, nil
+}
+
+func (r *fakeMotoRepo) DeactivateMissing(ctx context.Context, source string, seenSince time.Time) (int, error) {
+	r.reconciled++
+	return 0, nil
+}
+
+// fakeSalonRepo - справочник салонов в памяти по имени; на салоне broken запись падает.
+type fakeSalonRepo struct {
+	salons []domain.Salon
+	broken string
+}
+
+func (r *fakeSalonRepo) GetOrCreate(ctx context.Context, salon domain.Salon) (domain.Salon, error) {
+	for _, stored := range r.salons {
+		if stored.Name == salon.Name {
+			return stored, nil
+		}
+	}
+	return r.Upsert(ctx, salon)
+}
+
+func (r *fakeSalonRepo) Upsert(ctx context.Context, salon domain.Salon) (domain.Salon, error) {
+	if salon.Name == r.broken {
+		return domain.Salon{}, fmt.Errorf("%w: broken salon %s", domain.InternalError, salon.Name)
+	}
+	for i, stored := range r.salons {
+		if stored.Name == salon.Name {
+			salon.ID = stored.ID
+			r.salons[i] = salon
+			return salon, nil
+		}
+	}
+	salon.ID = uint(len(r.salons) + 1)
+	r.salons = append(r.salons, salon)
+	return salon, nil
+}
+
+func (r *fakeSalonRepo) Read(ctx context.Context, salonID uint) (domain.Salon, error) {
+	for _, stored := range r.salons {
+		if stored.ID == salonID {
+			return stored, nil
+		}
+	}
+	return domain.Salon{}, domain.RecordNotFound
+}
+
+func (r *fakeSalonRepo) List(ctx context.Context, filter domain.SalonFilter) ([]domain.Salon, error) {
+	var salons []domain.Salon
+	for _, stored := range r.salons {
+		if filter.City == "" || strings.EqualFold(stored.City, filter.City) {
+			salons = append(salons, stored)
+		}
+	}
+	return salons, nil
+}
+
+// fakeDriftHistoryRepo хранит историю обходов в памяти, его можно передать "перезапущенному" сервису.
+type fakeDriftHistoryRepo struct {
+	reports []domain.ParseReport
+}
+
+func (r *fakeDriftHistoryRepo) Append(ctx context.Context, report domain.ParseReport) error {
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func (r *fakeDriftHistoryRepo) Recent(ctx context.Context, source string, limit int) ([]domain.ParseReport, error) {
+	var reports []domain.ParseReport
+	for i := len(r.reports) - 1; i >= 0 && (limit <= 0 || len(reports) < limit); i-- {
+		if r.reports[i].Source == source {
+			reports = append(reports, r.reports[i])
+		}
+	}
+	return reports, nil
+}
+
+// fakeLockRepo - блокировка в памяти; held - ее держит другой экземпляр.
+type fakeLockRepo struct {
+	held bool
+}
+
+func (l *fakeLockRepo) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	if l.held {
+		return nil, false, nil
+	}
+	l.held = true
+	return func() { l.held = false }, true, nil
+}
+
+// fakeSyncRunRepo копит журнал обходов в памяти.
+type fakeSyncRunRepo struct {
+	runs []domain.SyncRun
+}
+
+func (r *fakeSyncRunRepo) Create(ctx context.Context, run domain.SyncRun) (domain.SyncRun, error) {
+	r.runs = append(r.runs, run)
+	return run, nil
+}
+
+func (r *fakeSyncRunRepo) List(ctx context.Context, filter domain.SyncRunFilter) ([]domain.SyncRun, error) {
+	return r.runs, nil
+}
+
+// memJobRepo хранит задачи в памяти вместо базы.
+type memJobRepo struct {
+	mu sync.Mutex
+	jobs map[string]domain.ParseJob
+}
+
+func (r *memJobRepo) Create(ctx context.Context, job domain.ParseJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[job.ID] = job
+	return nil
+}
+
+// Update, как и в базе, не затирает просьбу об отмене
+func (r *memJobRepo) Update(ctx context.Context, job domain.ParseJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job.CancelRequested = r.jobs[job.ID].CancelRequested
+	r.jobs[job.ID] = job
+	return nil
+}
+
+func (r *memJobRepo) Read(ctx context.Context, jobID string) (domain.ParseJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[jobID]
+	if !ok {
+		return domain.ParseJob{}, domain.RecordNotFound
+	}
+	return job, nil
+}
+
+func (r *memJobRepo) RunningOwners(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var owners []string
+	for _, job := range r.jobs {
+		if job.Status == domain.JobStatusRunning && !slices.Contains(owners, job.Owner) {
+			owners = append(owners, job.Owner)
+		}
+	}
+	return owners, nil
+}
+
+func (r *memJobRepo) InterruptOwner(ctx context.Context, owner string, at time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for id, job := range r.jobs {
+		if job.Status == domain.JobStatusRunning && job.Owner == owner {
+			job.Status = domain.JobStatusInterrupted
+			job.FinishedAt = &at
+			r.jobs[id] = job
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *memJobRepo) RequestCancel(ctx context.Context, jobID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[jobID]
+	if !ok || job.Status != domain.JobStatusRunning {
+		return false, nil
+	}
+	job.CancelRequested = true
+	r.jobs[jobID] = job
+	return true, nil
+}
+
+// memLockRepo - именованные блокировки в памяти, общие для "экземпляров" в тесте.
+type memLockRepo struct {
+	mu sync.Mutex
+	held map[string]bool
+	takes map[string]int // сколько раз бралась блокировка, чтобы unlock снимал только свою
+}
+
+func (l *memLockRepo) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[name] {
+		return nil, false, nil
+	}
+	if l.takes == nil {
+		l.takes = make(map[string]int)
+	}
+	l.held[name] = true
+	l.takes[name]++
+	take := l.takes[name]
+
+	// Как pg_advisory_unlock из другой сессии: чужую блокировку unlock не снимает
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.takes[name] == take {
+			delete(l.held, name)
+		}
+	}, true, nil
+}
+
+// names возвращает занятые блокировки.
+func (l *memLockRepo) names() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Sorted(maps.Keys(l.held))
+}
+
+// drop снимает блокировку, как Postgres при остановке экземпляра.
+func (l *memLockRepo) drop(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.held, name)
+}
+
+// fakeJobMotoService сообщает о двух страницах и ждет release или отмены.
+type fakeJobMotoService struct {
+	usecase.MotoService
+	progressed chan struct{}
+	release chan error
+
+	mu sync.Mutex
+	locked bool // обход уже идет: задачей, планировщиком или в другом экземпляре
+}
+
+func (s *fakeJobMotoService) CheckParseOptions(ctx context.Context, opts domain.ParseOptions) error {
+	if opts.Source == "unknown" {
+		return fmt.Errorf("%w: %q", domain.SourceNotFound, opts.Source)
+	}
+	return nil
+}
+
+func (s *fakeJobMotoService) LockSync(ctx context.Context) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked {
+		return nil, domain.SyncAlreadyRunning
+	}
+	s.locked = true
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.locked = false
+	}, nil
+}
+
+func (s *fakeJobMotoService) ParseAndUpdateLocked(ctx context.Context, opts domain.ParseOptions) (domain.ParseResult, error) {
+	opts.NotifyProgress(domain.ParseProgress{Pages: 1})
+	opts.NotifyProgress(domain.ParseProgress{Pages: 1, Upserted: 5})
+	s.progressed <- struct{}{}
+
+	select {
+	case err := <-s.release:
+		return domain.ParseResult{
+			Upserted: 5,
+			Reports: []domain.ParseReport{{PagesFetched: 2}},
+		}, err
+	case <-ctx.Done():
+		// Обход прервали до итога
+		return domain.ParseResult{}, ctx.Err()
+	}
+}
+
+func newTestJobService(t *testing.T, repo *memJobRepo, locks *memLockRepo) (usecase.JobService, *fakeJobMotoService) {
+	t.Helper()
+
+	svc := &fakeJobMotoService{progressed: make(chan struct{}), release: make(chan error)}
+	jobs, err := usecase.NewJobService(logger.NewLogger(), svc, repo, locks)
+	if err != nil {
+		t.Fatalf("new job service: %v", err)
+	}
+	t.Cleanup(jobs.Close)
+	return jobs, svc
+}
+
+func waitJob(t *testing.T, jobs usecase.JobService, id string) domain.ParseJob {
+	t.Helper()
+
+	return eventually(t, 5*time.Second, func() domain.ParseJob {
+		job, err := jobs.GetJob(context.Background(), id)
+		if err != nil {
+			t.Fatalf("get job: %v", err)
+		}
+		return job
+	}, domain.ParseJob.Finished)
+}
+
+// fakeSyncService блокирует обход, пока тест не отпустит его через release.
+type fakeSyncService struct {
+	usecase.MotoService
+	started chan struct{}
+	release chan error
+}
+
+func (s *fakeSyncService) ParseAndUpdateAllMoto(ctx context.Context, opts domain.ParseOptions) (domain.ParseResult, error) {
+	s.started <- struct{}{}
+	return domain.ParseResult{Upserted: 3}, <-s.release
+}
+
+func waitScheduler(t *testing.T, scheduler usecase.SyncScheduler, ok func(domain.SchedulerStatus) bool) domain.SchedulerStatus {
+	t.Helper()
+
+	return eventually(t, 2*time.Second, func() domain.SchedulerStatus {
+		return scheduler.Status(context.Background())
+	}, ok)
+}
@@ -0,0 +1,470 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+// Как часто сохранять ход идущей задачи в базу и проверять, не попросили ли ее отменить
+const jobSaveInterval = 2 * time.Second
+
+// Блокировка "экземпляр жив", см. LockRepo; к имени добавляется id экземпляра
+const jobOwnerLockPrefix = "job-owner:"
+
+// Как часто проверять, что блокировка владельца все еще у экземпляра
+const jobOwnerCheckInterval = 2 * time.Second
+
+/*
+jobService запускает обход каталога в фоне. Пока задача идет,
+ее состояние живет в памяти и раз в jobSaveInterval сохраняется в базу;
+завершенные задачи читаются из базы. Блокировку обхода (MotoService.LockSync)
+задача берет еще в StartParse, поэтому занятость видна сразу - и когда
+обходит планировщик, и когда другой экземпляр приложения.
+
+Задача принадлежит экземпляру, который ее выполняет (ParseJob.Owner). Пока
+экземпляр жив, он держит блокировку jobOwnerLockPrefix+owner; если ее можно
+взять - экземпляр остановился, и его незавершенные задачи помечаются
+прерванными. Отмену задачи чужого экземпляра владелец подхватывает из базы.
+
+Блокировка держится на одном соединении из пула. Если соединение оборвется,
+Postgres снимет ее, хотя экземпляр жив, поэтому раз в jobOwnerCheckInterval
+экземпляр проверяет ее и, если она потеряна, берет снова и пишет ошибку в лог.
+Close отпускает ее при остановке.
+*/
+type jobService struct {
+	log Logger
+	motoSVC MotoService
+	jobRepo JobRepo
+	lockRepo LockRepo
+	owner string
+	ownerUnlock func() // под mu
+
+	stopCheck chan struct{}
+	checkStopped chan struct{}
+	jobs sync.WaitGroup
+
+	mu sync.Mutex
+	running map[string]*runningJob
+	closed bool
+}
+
+type runningJob struct {
+	job domain.ParseJob // под jobService.mu
+	cancel context.CancelFunc
+}
+
+/*
+NewJobService заводит экземпляру id владельца задач и помечает прерванными
+задачи экземпляров, которые остановились, не доведя их до конца.
+*/
+func NewJobService(
+	log Logger,
+	motoSVC MotoService,
+	jobRepo JobRepo,
+	lockRepo LockRepo,
+) (JobService, error) {
+	ctx := context.Background()
+	owner := rand.Text()
+
+	// Блокировку отпускает Close, а если экземпляр упадет - Postgres
+	ownerUnlock, locked, err := lockRepo.TryLock(ctx, jobOwnerLockPrefix+owner)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, fmt.Errorf("%w: job owner %s is already locked", domain.InternalError, owner)
+	}
+
+	s := &jobService{
+		log: log,
+		motoSVC: motoSVC,
+		jobRepo: jobRepo,
+		lockRepo: lockRepo,
+		owner: owner,
+		ownerUnlock: ownerUnlock,
+		stopCheck: make(chan struct{}),
+		checkStopped: make(chan struct{}),
+		running: make(map[string]*runningJob),
+	}
+
+	owners, err := jobRepo.RunningOwners(ctx)
+	if err != nil {
+		ownerUnlock()
+		return nil, err
+	}
+	for _, o := range owners {
+		if _, err := s.interruptOrphaned(ctx, o); err != nil {
+			ownerUnlock()
+			return nil, err
+		}
+	}
+
+	go s.checkOwnerLock()
+
+	return s, nil
+}
+
+// checkOwnerLock следит за блокировкой владельца, пока не закроют stopCheck.
+func (s *jobService) checkOwnerLock() {
+	defer close(s.checkStopped)
+
+	ticker := time.NewTicker(jobOwnerCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCheck:
+			return
+		case <-ticker.C:
+		}
+
+		// id владельца случайный, поэтому занята блокировка может быть только нами
+		unlock, locked, err := s.lockRepo.TryLock(context.Background(), jobOwnerLockPrefix+s.owner)
+		if err != nil {
+			s.log.Error("JobService_checkOwnerLock: check job owner lock error", "owner", s.owner, "err", err)
+			continue
+		}
+		if !locked {
+			continue
+		}
+
+		// Блокировку удалось взять - значит, наше соединение ее потеряло, и другой
+		// экземпляр мог уже пометить наши задачи прерванными
+		s.mu.Lock()
+		lost := s.ownerUnlock
+		s.ownerUnlock = unlock
+		running := len(s.running)
+		s.mu.Unlock()
+		lost()
+
+		s.log.Error("JobService_checkOwnerLock: job owner lock was lost and taken again, running jobs may be marked interrupted", "owner", s.owner, "running", running)
+	}
+}
+
+/*
+Close отменяет идущие задачи, дожидается, пока они сохранят итог, и отпускает
+блокировку владельца. Новые задачи после Close не запускаются.
+*/
+func (s *jobService) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for _, rj := range s.running {
+		rj.cancel()
+	}
+	s.mu.Unlock()
+
+	s.jobs.Wait()
+
+	close(s.stopCheck)
+	<-s.checkStopped
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ownerUnlock()
+}
+
+// interruptOrphaned помечает прерванными задачи owner, если этот экземпляр уже не работает.
+func (s *jobService) interruptOrphaned(ctx context.Context, owner string) (bool, error) {
+	if owner == s.owner {
+		return false, nil
+	}
+
+	unlock, locked, err := s.lockRepo.TryLock(ctx, jobOwnerLockPrefix+owner)
+	if err != nil {
+		return false, err
+	}
+	if !locked {
+		// Экземпляр жив, его задачи идут
+		return false, nil
+	}
+	defer unlock()
+
+	interrupted, err := s.jobRepo.InterruptOwner(ctx, owner, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if interrupted > 0 {
+		s.log.Info("JobService: jobs of a stopped instance interrupted", "owner", owner, "count", interrupted)
+	}
+	return true, nil
+}
+
+func (s *jobService) StartParse(
+	ctx context.Context,
+	opts domain.ParseOptions,
+) (domain.ParseJob, error) {
+	s.log.Debug("JobService_StartParse: Start!", "source", opts.Source)
+
+	if opts.File != nil {
+		return domain.ParseJob{}, fmt.Errorf("%w: file imports are not run as jobs", domain.InvalidInput)
+	}
+	if err := s.motoSVC.CheckParseOptions(ctx, opts); err != nil {
+		return domain.ParseJob{}, err
+	}
+
+	// Close дожидается задач, поэтому задача учитывается до того, как заведется
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return domain.ParseJob{}, fmt.Errorf("%w: job service is closed", domain.InternalError)
+	}
+	s.jobs.Add(1)
+	s.mu.Unlock()
+	started := false
+	defer func() {
+		if !started {
+			s.jobs.Done()
+		}
+	}()
+
+	unlock, err := s.motoSVC.LockSync(ctx)
+	if err != nil {
+		return domain.ParseJob{}, err
+	}
+
+	now := time.Now()
+	job := domain.ParseJob{
+		ID: rand.Text(),
+		Status: domain.JobStatusRunning,
+		Owner: s.owner,
+		Source: opts.Source,
+		WithDetails: opts.WithDetails,
+		Snapshot: opts.Snapshot,
+		Force: opts.Force,
+		CreatedAt: now,
+		StartedAt: &now,
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		unlock()
+		s.log.Error("JobService_StartParse: create job error", "err", err)
+		return domain.ParseJob{}, err
+	}
+
+	// Задача живет дольше запроса, который ее создал
+	jobCtx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.running[job.ID] = &runningJob{job: job, cancel: cancel}
+	s.mu.Unlock()
+	started = true
+	go s.run(jobCtx, job.ID, opts, unlock)
+
+	s.log.Debug("JobService_StartParse: End!", "id", job.ID)
+	return job, nil
+}
+
+// run выполняет обход под блокировкой, взятой в StartParse, и снимает ее.
+func (s *jobService) run(ctx context.Context, jobID string, opts domain.ParseOptions, unlock func()) {
+	opts.Progress = func(p domain.ParseProgress) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		rj := s.running[jobID]
+		rj.job.PagesFetched += p.Pages
+		rj.job.Upserted += p.Upserted
+		rj.job.Failed += p.Failed
+	}
+
+	// Итог пишется только после того, как промежуточное сохранение остановилось,
+	// иначе оно могло бы затереть его старыми счетчиками
+	done := make(chan struct{})
+	saverStopped := make(chan struct{})
+	go func() {
+		defer close(saverStopped)
+		s.saveProgress(jobID, done)
+	}()
+
+	result, err := s.motoSVC.ParseAndUpdateLocked(ctx, opts)
+	unlock()
+	close(done)
+	<-saverStopped
+
+	s.mu.Lock()
+	rj := s.running[jobID]
+	job := rj.job
+	delete(s.running, jobID)
+	s.mu.Unlock()
+	defer rj.cancel()
+	defer s.jobs.Done()
+
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+
+	// После ошибки или отмены итог бывает неполным или пустым - тогда
+	// остается ход, накопленный через Progress
+	var pagesFetched int
+	for _, report := range result.Reports {
+		pagesFetched += report.PagesFetched
+	}
+	job.PagesFetched = max(job.PagesFetched, pagesFetched)
+	job.Upserted = max(job.Upserted, result.Upserted)
+	job.Failed = max(job.Failed, result.Failed)
+	job.Deactivated = result.Deactivated
+
+	switch {
+	case ctx.Err() != nil:
+		job.Status = domain.JobStatusCanceled
+	case err != nil:
+		job.Status = domain.JobStatusFailed
+		job.Errors = errorMessages(err)
+	default:
+		job.Status = domain.JobStatusSucceeded
+	}
+
+	if err := s.jobRepo.Update(context.Background(), job); err != nil {
+		s.log.Error("JobService_run: save finished job error", "id", jobID, "err", err)
+	}
+
+	s.log.Info("JobService_run: job finished", "id", jobID, "status", job.Status, "upserted", job.Upserted, "failed", job.Failed)
+}
+
+// saveProgress сохраняет ход задачи, пока не закроют done.
+func (s *jobService) saveProgress(jobID string, done <-chan struct{}) {
+	ticker := time.NewTicker(jobSaveInterval)
+	defer ticker.Stop()
+
+	var saved domain.ParseJob
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		s.checkCancelRequest(jobID)
+
+		s.mu.Lock()
+		job := s.running[jobID].job
+		s.mu.Unlock()
+
+		if job.PagesFetched == saved.PagesFetched && job.Upserted == saved.Upserted && job.Failed == saved.Failed {
+			continue
+		}
+		if err := s.jobRepo.Update(context.Background(), job); err != nil {
+			s.log.Error("JobService_saveProgress: save job error", "id", jobID, "err", err)
+			continue
+		}
+		saved = job
+	}
+}
+
+// checkCancelRequest отменяет задачу, если ее отмену попросили через другой экземпляр.
+func (s *jobService) checkCancelRequest(jobID string) {
+	stored, err := s.jobRepo.Read(context.Background(), jobID)
+	if err != nil {
+		s.log.Error("JobService_checkCancelRequest: read job error", "id", jobID, "err", err)
+		return
+	}
+	if !stored.CancelRequested {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rj, ok := s.running[jobID]; ok {
+		rj.cancel()
+		s.log.Info("JobService_checkCancelRequest: job cancel requested by another instance", "id", jobID)
+	}
+}
+
+// errorMessages раскладывает ошибку из errors.Join по одной на источник.
+func errorMessages(err error) []string {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		var messages []string
+		for _, e := range joined.Unwrap() {
+			messages = append(messages, e.Error())
+		}
+		return messages
+	}
+	return []string{err.Error()}
+}
+
+func (s *jobService) GetJob(
+	ctx context.Context,
+	jobID string,
+) (domain.ParseJob, error) {
+	s.mu.Lock()
+	rj, ok := s.running[jobID]
+	var job domain.ParseJob
+	if ok {
+		job = rj.job
+	}
+	s.mu.Unlock()
+
+	if ok {
+		return job, nil
+	}
+	return s.readJob(ctx, jobID)
+}
+
+// readJob читает задачу из базы. Незавершенную задачу остановившегося
+// экземпляра сначала помечает прерванной, чтобы не отдавать ее как идущую.
+func (s *jobService) readJob(ctx context.Context, jobID string) (domain.ParseJob, error) {
+	job, err := s.jobRepo.Read(ctx, jobID)
+	if err != nil || job.Finished() {
+		return job, err
+	}
+
+	interrupted, err := s.interruptOrphaned(ctx, job.Owner)
+	if err != nil {
+		s.log.Error("JobService_readJob: check job owner error", "id", jobID, "owner", job.Owner, "err", err)
+		return job, nil
+	}
+	if interrupted {
+		return s.jobRepo.Read(ctx, jobID)
+	}
+	return job, nil
+}
+
+func (s *jobService) CancelJob(
+	ctx context.Context,
+	jobID string,
+) (domain.ParseJob, error) {
+	s.log.Debug("JobService_CancelJob: Start!", "id", jobID)
+
+	s.mu.Lock()
+	rj, ok := s.running[jobID]
+	var job domain.ParseJob
+	if ok {
+		rj.cancel()
+		job = rj.job
+	}
+	s.mu.Unlock()
+
+	if ok {
+		s.log.Info("JobService_CancelJob: job cancel requested", "id", jobID)
+		return job, nil
+	}
+
+	job, err := s.readJob(ctx, jobID)
+	if err != nil {
+		return domain.ParseJob{}, err
+	}
+	if !job.Finished() {
+		// Задачу выполняет другой экземпляр, он подхватит отмену из базы
+		requested, err := s.jobRepo.RequestCancel(ctx, jobID)
+		if err != nil {
+			return domain.ParseJob{}, err
+		}
+		if requested {
+			s.log.Info("JobService_CancelJob: job cancel requested from its owner", "id", jobID, "owner", job.Owner)
+			job.CancelRequested = true
+			return job, nil
+		}
+
+		if job, err = s.jobRepo.Read(ctx, jobID); err != nil {
+			return domain.ParseJob{}, err
+		}
+	}
+	return job, fmt.Errorf("%w: job %s is %s", domain.JobFinished, jobID, job.Status)
+}
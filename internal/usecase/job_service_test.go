@@ -0,0 +1,175 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+func TestJobService_RunsParseInBackground(t *testing.T) {
+	ctx := context.Background()
+	repo := &memJobRepo{jobs: make(map[string]domain.ParseJob)}
+	jobs, svc := newTestJobService(t, repo, &memLockRepo{held: make(map[string]bool)})
+
+	// Заведомо неудачный запуск задачу не заводит
+	if _, err := jobs.StartParse(ctx, domain.ParseOptions{Source: "unknown"}); !errors.Is(err, domain.SourceNotFound) {
+		t.Errorf("unknown source: expected source not found, got %v", err)
+	}
+	if len(repo.jobs) != 0 {
+		t.Errorf("job created for unknown source: %+v", repo.jobs)
+	}
+
+	job, err := jobs.StartParse(ctx, domain.ParseOptions{Source: "mr-moto"})
+	if err != nil {
+		t.Fatalf("start parse: %v", err)
+	}
+	<-svc.progressed
+
+	running, err := jobs.GetJob(ctx, job.ID)
+	if err != nil || running.Status != domain.JobStatusRunning || running.PagesFetched != 2 || running.Upserted != 5 {
+		t.Errorf("unexpected running job: %+v, err: %v", running, err)
+	}
+	if _, err := jobs.StartParse(ctx, domain.ParseOptions{}); !errors.Is(err, domain.SyncAlreadyRunning) {
+		t.Errorf("second job: expected already running, got %v", err)
+	}
+
+	svc.release <- errors.Join(errors.New("source a failed"), errors.New("source b failed"))
+	finished := waitJob(t, jobs, job.ID)
+	if finished.Status != domain.JobStatusFailed || len(finished.Errors) != 2 || finished.FinishedAt == nil {
+		t.Errorf("unexpected finished job: %+v", finished)
+	}
+	if _, err := jobs.CancelJob(ctx, job.ID); !errors.Is(err, domain.JobFinished) {
+		t.Errorf("cancel finished job: expected job finished, got %v", err)
+	}
+
+	job, err = jobs.StartParse(ctx, domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("start parse: %v", err)
+	}
+	<-svc.progressed
+
+	if _, err := jobs.CancelJob(ctx, job.ID); err != nil {
+		t.Fatalf("cancel job: %v", err)
+	}
+	// Пустой итог отмененного обхода не затирает ход задачи
+	if canceled := waitJob(t, jobs, job.ID); canceled.Status != domain.JobStatusCanceled || canceled.PagesFetched != 2 || canceled.Upserted != 5 {
+		t.Errorf("unexpected canceled job: %+v", canceled)
+	}
+}
+
+func TestJobService_JobsOfOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	repo := &memJobRepo{jobs: make(map[string]domain.ParseJob)}
+	locks := &memLockRepo{held: make(map[string]bool)}
+
+	first, svc := newTestJobService(t, repo, locks)
+	job, err := first.StartParse(ctx, domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("start parse: %v", err)
+	}
+	<-svc.progressed
+
+	// Второй экземпляр чужую живую задачу не трогает
+	second, _ := newTestJobService(t, repo, locks)
+	if stored, err := second.GetJob(ctx, job.ID); err != nil || stored.Status != domain.JobStatusRunning || stored.Owner != job.Owner {
+		t.Fatalf("running job of another instance: %+v, err: %v", stored, err)
+	}
+
+	// Отмену через второй экземпляр подхватывает владелец
+	requested, err := second.CancelJob(ctx, job.ID)
+	if err != nil || !requested.CancelRequested {
+		t.Fatalf("cancel job of another instance: %+v, err: %v", requested, err)
+	}
+	if canceled := waitJob(t, second, job.ID); canceled.Status != domain.JobStatusCanceled {
+		t.Errorf("unexpected canceled job: %+v", canceled)
+	}
+
+	job, err = first.StartParse(ctx, domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("start parse: %v", err)
+	}
+	<-svc.progressed
+
+	// Первый экземпляр остановился - его задачу больше никто не выполняет
+	locks.drop("job-owner:" + job.Owner)
+	if stored, err := second.GetJob(ctx, job.ID); err != nil || stored.Status != domain.JobStatusInterrupted || stored.FinishedAt == nil {
+		t.Errorf("job of a stopped instance is not interrupted: %+v, err: %v", stored, err)
+	}
+}
+
+func TestJobService_OwnerLockLost(t *testing.T) {
+	locks := &memLockRepo{held: make(map[string]bool)}
+	newTestJobService(t, &memJobRepo{jobs: make(map[string]domain.ParseJob)}, locks)
+
+	owner := locks.names()
+	if len(owner) != 1 || !strings.HasPrefix(owner[0], "job-owner:") {
+		t.Fatalf("unexpected locks of a new instance: %v", owner)
+	}
+
+	// Соединение с блокировкой оборвалось - экземпляр берет ее снова
+	locks.drop(owner[0])
+	eventually(t, 5*time.Second, locks.names, func(names []string) bool {
+		return slices.Equal(names, owner)
+	})
+}
+
+func TestJobService_Close(t *testing.T) {
+	ctx := context.Background()
+	repo := &memJobRepo{jobs: make(map[string]domain.ParseJob)}
+	locks := &memLockRepo{held: make(map[string]bool)}
+
+	jobs, svc := newTestJobService(t, repo, locks)
+	job, err := jobs.StartParse(ctx, domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("start parse: %v", err)
+	}
+	<-svc.progressed
+
+	jobs.Close()
+	if stored, err := repo.Read(ctx, job.ID); err != nil || stored.Status != domain.JobStatusCanceled {
+		t.Errorf("job is not canceled on close: %+v, err: %v", stored, err)
+	}
+	if names := locks.names(); len(names) != 0 {
+		t.Errorf("locks are not released on close: %v", names)
+	}
+	if _, err := jobs.StartParse(ctx, domain.ParseOptions{}); err == nil {
+		t.Errorf("job started after close")
+	}
+}
+
+func TestJobService_BusyWithOtherSync(t *testing.T) {
+	ctx := context.Background()
+	repo := &memJobRepo{jobs: make(map[string]domain.ParseJob)}
+	jobs, svc := newTestJobService(t, repo, &memLockRepo{held: make(map[string]bool)})
+
+	// Каталог обходит планировщик - задача не заводится, а не падает следом
+	svc.locked = true
+	if _, err := jobs.StartParse(ctx, domain.ParseOptions{}); !errors.Is(err, domain.SyncAlreadyRunning) {
+		t.Fatalf("expected already running, got %v", err)
+	}
+	if len(repo.jobs) != 0 {
+		t.Errorf("job created while another sync is running: %+v", repo.jobs)
+	}
+
+	svc.locked = false
+	job, err := jobs.StartParse(ctx, domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("start parse: %v", err)
+	}
+	<-svc.progressed
+	svc.release <- nil
+	if finished := waitJob(t, jobs, job.ID); finished.Status != domain.JobStatusSucceeded {
+		t.Errorf("unexpected finished job: %+v", finished)
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	if svc.locked {
+		t.Errorf("sync lock is not released after the job")
+	}
+}
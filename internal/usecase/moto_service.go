@@ -2,25 +2,64 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/vvetta/electoral_system/internal/domain"
 )
 
+// Сколько мотоциклов писать в базу одной транзакцией
+const upsertBatchSize = 200
+
+// Блокировка обхода каталога, см. LockRepo
+const syncLockName = "catalog-sync"
+
 type motoService struct {
 	log Logger
 	motoRepo MotoRepo
-	motoParser MotoParser
+	salonRepo SalonRepo
+	sources *SourceRegistry
+	drift domain.DriftPolicy
+	driftHistoryRepo DriftHistoryRepo
+	lockRepo LockRepo
+	syncRunRepo SyncRunRepo
+
+	// Обходы не должны идти одновременно: ручной запуск, импорт и планировщик
+	// писали бы одни и те же строки наперегонки. syncMu - внутри экземпляра,
+	// lockRepo - между экземплярами приложения
+	syncMu sync.Mutex
+
+	reportsMu sync.RWMutex
+	lastReports []domain.ParseReport
+
+	healthMu sync.RWMutex
+	health map[string]domain.SourceHealth
 }
 
 func NewMotoService(
 	log Logger, 
 	motoRepo MotoRepo, 
-	motoParser MotoParser,
+	salonRepo SalonRepo,
+	sources *SourceRegistry,
+	drift domain.DriftPolicy,
+	driftHistoryRepo DriftHistoryRepo,
+	lockRepo LockRepo,
+	syncRunRepo SyncRunRepo,
 ) MotoService {
 	return &motoService{
 		log: log,
 		motoRepo: motoRepo,
-		motoParser: motoParser,
+		salonRepo: salonRepo,
+		sources: sources,
+		drift: drift,
+		driftHistoryRepo: driftHistoryRepo,
+		lockRepo: lockRepo,
+		syncRunRepo: syncRunRepo,
+		health: make(map[string]domain.SourceHealth),
 	}
 }
 
@@ -39,32 +78,478 @@ func (s *motoService)	GetAllMoto(
 
 func (s *motoService) ParseAndUpdateAllMoto(
 	ctx context.Context,
-) ([]domain.Moto, error) {
-	s.log.Debug("MotoService_ParseAndUpdateAllMoto: Start!")
+	opts domain.ParseOptions,
+) (domain.ParseResult, error) {
+	if err := s.CheckParseOptions(ctx, opts); err != nil {
+		return domain.ParseResult{}, err
+	}
+
+	unlock, err := s.LockSync(ctx)
+	if err != nil {
+		return domain.ParseResult{}, err
+	}
+	defer unlock()
+
+	return s.ParseAndUpdateLocked(ctx, opts)
+}
+
+/*
+CheckParseOptions проверяет запуск до обхода: источник есть, снимок у него есть,
+файл и снимок заданы вместе с источником. Так фоновая задача не заводится
+под заведомо неудачный запуск.
+*/
+func (s *motoService) CheckParseOptions(
+	ctx context.Context,
+	opts domain.ParseOptions,
+) error {
+	if opts.File != nil && opts.Source == "" {
+		return fmt.Errorf("%w: source is required to import a file", domain.InvalidInput)
+	}
+	if opts.Snapshot != "" && opts.Source == "" {
+		return fmt.Errorf("%w: source is required to replay a snapshot", domain.InvalidInput)
+	}
+	if opts.Commit && opts.Snapshot == "" {
+		return fmt.Errorf("%w: commit is only for snapshot replays", domain.InvalidInput)
+	}
+	if opts.Source == "" {
+		return nil
+	}
+
+	src, err := s.sources.Get(opts.Source)
+	if err != nil {
+		s.log.Debug("MotoService_CheckParseOptions: get source error", "source", opts.Source, "err", err)
+		return err
+	}
+
+	if opts.Snapshot != "" {
+		parser, ok := src.Parser.(SnapshotParser)
+		if !ok {
+			return fmt.Errorf("%w: source %q does not keep snapshots", domain.SnapshotNotFound, src.Name)
+		}
+		return parser.CheckSnapshot(opts.Snapshot)
+	}
 
-	motos, err := s.motoParser.GetAllMoto()
+	return nil
+}
+
+// LockSync берет блокировку обхода: syncMu внутри экземпляра и lockRepo между экземплярами.
+func (s *motoService) LockSync(ctx context.Context) (func(), error) {
+	if !s.syncMu.TryLock() {
+		s.log.Info("MotoService_LockSync: another sync is running")
+		return nil, domain.SyncAlreadyRunning
+	}
+
+	unlock, locked, err := s.lockRepo.TryLock(ctx, syncLockName)
 	if err != nil {
-		s.log.Error("MotoService_ParseAndUpdateAllMoto: parsing moto error", "err", err)
+		s.syncMu.Unlock()
+		s.log.Error("MotoService_LockSync: take sync lock error", "err", err)
 		return nil, err
 	}
+	if !locked {
+		s.syncMu.Unlock()
+		s.log.Info("MotoService_LockSync: another instance is syncing")
+		return nil, fmt.Errorf("%w: another instance is syncing", domain.SyncAlreadyRunning)
+	}
 
-	//TODO тут можно использовать канал с мотоциклами и сделать несколько воркеров.
-	//TODO функция хрень. Мотоциклы приходят без id, поэтому при каждом запуске будет происходить запись
-	//можно попробовать считать хеш от всех полей и сделать это поле уникальным.
-	var updatedMotos []domain.Moto
-	for i, moto := range motos {
-		moto.ID = uint(i)
-		updatedMoto, err := s.motoRepo.Update(ctx, moto)
+	return func() {
+		unlock()
+		s.syncMu.Unlock()
+	}, nil
+}
+
+func (s *motoService) ParseAndUpdateLocked(
+	ctx context.Context,
+	opts domain.ParseOptions,
+) (domain.ParseResult, error) {
+	s.log.Debug("MotoService_ParseAndUpdateAllMoto: Start!", "source", opts.Source, "with_details", opts.WithDetails, "snapshot", opts.Snapshot, "commit", opts.Commit, "force", opts.Force)
+
+	var result domain.ParseResult
+
+	sources := s.sources.Enabled()
+	if opts.Source != "" {
+		src, err := s.sources.Get(opts.Source)
 		if err != nil {
-			s.log.Error("MotoService_ParseAndUpdateAllMoto: update moto error", "id", moto.ID, "err", err)
-			continue
+			s.log.Error("MotoService_ParseAndUpdateAllMoto: get source error", "source", opts.Source, "err", err)
+			return result, err
 		}
+		sources = []MotoSource{src}
+	}
 
-		updatedMotos = append(updatedMotos, updatedMoto)
+	// Ошибка одного источника не должна мешать обходу остальных
+	var errs []error
+	for _, src := range sources {
+		startedAt := time.Now()
+		report, err := s.parseAndUpdateSource(ctx, src, opts)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		s.recordSyncRun(ctx, opts.SyncRunKind(), startedAt, report, err)
+
+		result.Upserted += report.Inserted + report.Updated
+		result.Failed += report.Failed
+		result.Deactivated += report.Deactivated
+		result.Reports = append(result.Reports, report)
 	}
 
-	s.log.Debug("MotoService_ParseAndUpdateAlLMoto: End!")
-	return updatedMotos, nil
+	s.reportsMu.Lock()
+	s.lastReports = result.Reports
+	s.reportsMu.Unlock()
+
+	s.log.Debug("MotoService_ParseAndUpdateAlLMoto: End!", "upserted", result.Upserted, "failed", result.Failed)
+	return result, errors.Join(errs...)
+}
+
+/*
+parseAndUpdateSource - конвейер "парсер -> запись в базу".
+Парсер отдает мотоциклы в канал по мере разбора страниц, а writeMotos
+складывает их пачками по upsertBatchSize и пишет одной транзакцией.
+Так в базу не идет по запросу на мотоцикл, и в памяти не висит весь каталог.
+
+После обхода отчет сравнивается с прошлыми (domain.DetectDrift). При
+DriftActionRefuse разметка сначала проверяется по первой странице
+(domain.DetectFirstPageDrift): если она "уплыла", обход останавливается,
+не записав ничего. Дрейф, который виден только в конце (карточек стало
+меньше), записанное уже не откатывает, но обход не принимается: объявления
+не снимаются с показа и источник помечается failed. При DriftActionFlag
+обход только помечается.
+
+Разбор снимка без opts.Commit - пробный: мотоциклы не пишутся, в отчете
+только статистика и дрейф, здоровье источника и история не меняются.
+*/
+func (s *motoService) parseAndUpdateSource(
+	ctx context.Context,
+	src MotoSource,
+	opts domain.ParseOptions,
+) (domain.ParseReport, error) {
+	// Выгрузки дилера бывают любого размера, сравнивать их между собой бессмысленно
+	checkDrift := opts.File == nil
+	dryRun := opts.DryRun()
+	refuse := checkDrift && s.drift.Action == domain.DriftActionRefuse && !opts.Force && !dryRun
+
+	var history []domain.ParseReport
+	if checkDrift {
+		var err error
+		history, err = s.driftHistoryRepo.Recent(ctx, src.Name, s.drift.HistorySize)
+		if err != nil {
+			s.log.Error("MotoService_ParseAndUpdateAllMoto: load drift history error", "source", src.Name, "err", err)
+			s.setHealth(domain.SourceHealth{Source: src.Name, Status: domain.HealthFailed, Error: err.Error()})
+			return domain.ParseReport{Source: src.Name}, err
+		}
+	}
+
+	// Пишется из горутины парсера, читается после закрытия канала
+	var firstPageDrift []domain.DriftIssue
+	if refuse {
+		opts.FirstPage = func(partial domain.ParseReport) error {
+			firstPageDrift = domain.DetectFirstPageDrift(partial, history, s.drift)
+			if len(firstPageDrift) > 0 {
+				return fmt.Errorf("%w: source %q: %s", domain.SchemaDrift, src.Name, firstPageDrift[0].Message)
+			}
+			return nil
+		}
+	}
+
+	motos := make(chan domain.Moto, upsertBatchSize)
+	seenAt := time.Now()
+
+	var report domain.ParseReport
+	var parseErr error
+	go func() {
+		defer close(motos)
+		report, parseErr = src.Parser.StreamAllMoto(ctx, opts, motos)
+	}()
+
+	// Оба варианта читают канал до закрытия, т.е. до завершения парсера
+	var written domain.UpsertStats
+	var failed int
+	if dryRun {
+		for range motos {
+		}
+	} else {
+		written, failed = s.writeMotos(ctx, src.Name, seenAt, motos, opts)
+	}
+
+	report.Source = src.Name
+	report.Inserted, report.Updated, report.Failed = written.Inserted, written.Updated, failed
+	if parseErr != nil {
+		s.log.Error("MotoService_ParseAndUpdateAllMoto: parsing moto error", "source", src.Name, "err", parseErr)
+		report.Drift = firstPageDrift
+		if dryRun {
+			return report, parseErr
+		}
+		s.setHealth(domain.SourceHealth{
+			Source: src.Name,
+			Status: domain.HealthFailed,
+			Committed: written.Total() > 0,
+			CardsFound: report.CardsFound,
+			Issues: firstPageDrift,
+			Error: parseErr.Error(),
+		})
+		return report, parseErr
+	}
+
+	if checkDrift {
+		report.Drift = domain.DetectDrift(report, history, s.drift)
+	}
+
+	if dryRun {
+		s.log.Info("MotoService_ParseAndUpdateAllMoto: snapshot dry run", "source", src.Name, "snapshot", report.Snapshot, "cards_found", report.CardsFound, "drift", len(report.Drift))
+		return report, nil
+	}
+
+	if len(report.Drift) > 0 && refuse {
+		s.log.Error("MotoService_ParseAndUpdateAllMoto: schema drift, crawl is not accepted", "source", src.Name, "issues", len(report.Drift), "first", report.Drift[0].Message, "written", written.Total())
+		s.setHealth(domain.SourceHealth{
+			Source: src.Name,
+			Status: domain.HealthFailed,
+			Committed: written.Total() > 0,
+			CardsFound: report.CardsFound,
+			Issues: report.Drift,
+		})
+		return report, fmt.Errorf("%w: source %q: %s", domain.SchemaDrift, src.Name, report.Drift[0].Message)
+	}
+
+	if s.canReconcile(opts, report, failed) {
+		deactivated, err := s.motoRepo.DeactivateMissing(ctx, src.Name, seenAt)
+		if err != nil {
+			s.log.Error("MotoService_ParseAndUpdateAllMoto: deactivate missing motos error", "source", src.Name, "err", err)
+		}
+		report.Deactivated = deactivated
+	}
+
+	health := domain.SourceHealth{
+		Source: src.Name,
+		Status: domain.HealthOK,
+		Committed: true,
+		CardsFound: report.CardsFound,
+		Issues: report.Drift,
+	}
+	if len(report.Drift) > 0 {
+		s.log.Error("MotoService_ParseAndUpdateAllMoto: schema drift", "source", src.Name, "issues", len(report.Drift), "first", report.Drift[0].Message, "force", opts.Force)
+		health.Status = domain.HealthDegraded
+	}
+	// Обход с дрейфом не портит базу для сравнения, пока его явно не приняли через Force.
+	// Старый снимок нормой для новых обходов не становится
+	if checkDrift && opts.Snapshot == "" && (len(report.Drift) == 0 || opts.Force) {
+		if err := s.driftHistoryRepo.Append(ctx, report); err != nil {
+			s.log.Error("MotoService_ParseAndUpdateAllMoto: save drift history error", "source", src.Name, "err", err)
+		}
+	}
+	s.setHealth(health)
+
+	s.log.Info(
+		"MotoService_ParseAndUpdateAllMoto: parse report",
+		"source", src.Name,
+		"snapshot", report.Snapshot,
+		"pages", report.PagesFetched,
+		"cards_found", report.CardsFound,
+		"cards_rejected", report.CardsRejected,
+		"unknown_labels", len(report.UnknownLabels),
+		"unknown_moto_types", len(report.UnknownMotoTypes),
+		"drift", len(report.Drift),
+		"inserted", report.Inserted,
+		"updated", report.Updated,
+		"failed", report.Failed,
+		"deactivated", report.Deactivated,
+	)
+
+	return report, nil
+}
+
+func (s *motoService) writeMotos(
+	ctx context.Context,
+	source string,
+	seenAt time.Time,
+	motos <-chan domain.Moto,
+	opts domain.ParseOptions,
+) (domain.UpsertStats, int) {
+	var written domain.UpsertStats
+	var failed int
+	upsertOpts := opts.UpsertOptions()
+	batch := make([]domain.Moto, 0, upsertBatchSize)
+	salonIDs := make(map[string]uint) // имя салона -> id, чтобы не ходить в базу за каждым мотоциклом
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		stats, err := s.motoRepo.UpsertMany(ctx, batch, upsertOpts)
+		if err == nil {
+			written.Inserted += stats.Inserted
+			written.Updated += stats.Updated
+			opts.NotifyProgress(domain.ParseProgress{Upserted: stats.Total()})
+			batch = batch[:0]
+			return
+		}
+		s.log.Error("MotoService_ParseAndUpdateAllMoto: upsert batch error", "source", source, "size", len(batch), "err", err)
+
+		// Из-за одной плохой строки не теряем всю пачку: пишем по одной
+		var batchFailed int
+		for _, moto := range batch {
+			if ctx.Err() != nil {
+				batchFailed++
+				continue
+			}
+			stats, err := s.motoRepo.UpsertMany(ctx, []domain.Moto{moto}, upsertOpts)
+			if err != nil {
+				s.log.Error("MotoService_ParseAndUpdateAllMoto: upsert moto error", "source", source, "external_id", moto.ExternalID, "err", err)
+				batchFailed++
+				continue
+			}
+			written.Inserted += stats.Inserted
+			written.Updated += stats.Updated
+			opts.NotifyProgress(domain.ParseProgress{Upserted: stats.Total()})
+		}
+		failed += batchFailed
+		opts.NotifyProgress(domain.ParseProgress{Failed: batchFailed})
+
+		batch = batch[:0]
+	}
+
+	for moto := range motos {
+		moto.Source = source
+		if moto.ExternalID == "" {
+			moto.ExternalID = moto.ListingKey()
+		}
+		moto.SalonID = s.resolveSalon(ctx, moto, salonIDs)
+		if upsertOpts.MarkSeen {
+			moto.LastSeenAt = &seenAt
+			moto.DeactivatedAt = nil
+		}
+
+		batch = append(batch, moto)
+		if len(batch) == upsertBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	return written, failed
+}
+
+// recordSyncRun пишет обход источника в журнал. Ошибка журнала обход не валит.
+func (s *motoService) recordSyncRun(
+	ctx context.Context,
+	kind string,
+	startedAt time.Time,
+	report domain.ParseReport,
+	syncErr error,
+) {
+	run := domain.SyncRun{
+		Source: report.Source,
+		Kind: kind,
+		StartedAt: startedAt,
+		FinishedAt: time.Now(),
+		Pages: report.PagesFetched,
+		CardsFound: report.CardsFound,
+		Inserted: report.Inserted,
+		Updated: report.Updated,
+		Deactivated: report.Deactivated,
+		Failed: report.Failed,
+	}
+	if syncErr != nil {
+		run.Error = syncErr.Error()
+	}
+
+	// Отмененный обход тоже должен попасть в журнал
+	if _, err := s.syncRunRepo.Create(context.WithoutCancel(ctx), run); err != nil {
+		s.log.Error("MotoService_ParseAndUpdateAllMoto: record sync run error", "source", report.Source, "err", err)
+	}
+}
+
+/*
+canReconcile - можно ли считать обход полным и снимать с показа объявления,
+которых в нем не было. Нельзя, если часть мотоциклов не записалась (у них
+не обновился last_seen_at), если обход оборвался на max_page_count, если это
+загруженный файл (выгрузка может быть частичной) или старый снимок страниц,
+и если найден дрейф разметки - пока его явно не приняли через Force.
+*/
+func (s *motoService) canReconcile(opts domain.ParseOptions, report domain.ParseReport, failed int) bool {
+	if failed > 0 || report.Truncated || opts.File != nil || opts.Snapshot != "" {
+		return false
+	}
+	return len(report.Drift) == 0 || opts.Force
+}
+
+func (s *motoService) setHealth(health domain.SourceHealth) {
+	health.CheckedAt = time.Now()
+
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+
+	s.health[health.Source] = health
+}
+
+/*
+Health возвращает здоровье включенных источников и тех, что обходились
+по имени. Источник без обходов - HealthUnknown.
+*/
+func (s *motoService) Health(ctx context.Context) []domain.SourceHealth {
+	s.healthMu.RLock()
+	defer s.healthMu.RUnlock()
+
+	seen := make(map[string]bool)
+	var result []domain.SourceHealth
+	for _, src := range s.sources.Enabled() {
+		seen[src.Name] = true
+		health, ok := s.health[src.Name]
+		if !ok {
+			health = domain.SourceHealth{Source: src.Name, Status: domain.HealthUnknown}
+		}
+		result = append(result, health)
+	}
+	for name, health := range s.health {
+		if !seen[name] {
+			result = append(result, health)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Source < result[j].Source
+	})
+
+	return result
+}
+
+/*
+resolveSalon находит салон объявления по названию из строки "Мотосалон",
+незнакомые салоны создаются. Ошибка не мешает записи мотоцикла:
+он просто останется без салона до следующего обхода.
+*/
+func (s *motoService) resolveSalon(
+	ctx context.Context,
+	moto domain.Moto,
+	cache map[string]uint,
+) *uint {
+	name := strings.Join(strings.Fields(moto.Location), " ")
+	if name == "" {
+		return nil
+	}
+
+	if id, ok := cache[name]; ok {
+		return &id
+	}
+
+	salon, err := s.salonRepo.GetOrCreate(ctx, domain.Salon{Name: name, URL: moto.DealerURL})
+	if err != nil {
+		s.log.Error("MotoService_ParseAndUpdateAllMoto: resolve salon error", "salon", name, "err", err)
+		return nil
+	}
+
+	cache[name] = salon.ID
+	return &salon.ID
+}
+
+func (s *motoService) LastParseReports(ctx context.Context) []domain.ParseReport {
+	s.reportsMu.RLock()
+	defer s.reportsMu.RUnlock()
+
+	return s.lastReports
+}
+
+func (s *motoService) GetMotoClasses(ctx context.Context) []domain.MotoClassInfo {
+	return domain.MotoClasses()
 }
 
 func (s *motoService) UpdateMoto(
@@ -85,5 +570,56 @@ func (s *motoService) GetMotosByFilter(
 	ctx context.Context,
 	filter domain.MotoFilter,
 ) ([]domain.Moto, error) {
-	return s.motoRepo.GetMotosByFilter(ctx, filter)
+	motos, err := s.motoRepo.GetMotosByFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	markPriceDrops(motos)
+	return motos, nil
+}
+
+// markPriceDrops проставляет RecentPriceDrop мотоциклам из выдачи.
+func markPriceDrops(motos []domain.Moto) {
+	now := time.Now()
+	for i := range motos {
+		motos[i].RecentPriceDrop = motos[i].PriceDroppedWithin(domain.RecentPriceDropWindow, now)
+	}
+}
+
+func (s *motoService) GetSyncRuns(
+	ctx context.Context,
+	filter domain.SyncRunFilter,
+) ([]domain.SyncRun, error) {
+	switch filter.Status {
+	case "", domain.SyncRunStatusOK, domain.SyncRunStatusFailed:
+	default:
+		return nil, fmt.Errorf("%w: unknown sync run status %q", domain.InvalidInput, filter.Status)
+	}
+	switch filter.Kind {
+	case "", domain.SyncRunKindCrawl, domain.SyncRunKindSnapshot, domain.SyncRunKindFile:
+	default:
+		return nil, fmt.Errorf("%w: unknown sync run kind %q", domain.InvalidInput, filter.Kind)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = domain.DefaultSyncRunsLimit
+	}
+	if filter.Limit > domain.MaxSyncRunsLimit {
+		filter.Limit = domain.MaxSyncRunsLimit
+	}
+
+	return s.syncRunRepo.List(ctx, filter)
+}
+
+func (s *motoService) GetPriceHistory(
+	ctx context.Context,
+	motoID uint,
+) ([]domain.PricePoint, error) {
+	// Чтобы на несуществующий id ответить RecordNotFound, а не пустой историей
+	if _, err := s.motoRepo.Read(ctx, motoID); err != nil {
+		return nil, err
+	}
+
+	return s.motoRepo.GetPriceHistory(ctx, motoID)
 }
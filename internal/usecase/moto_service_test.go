@@ -2,67 +2,58 @@ package usecase_test
 
 import (
 	"context"
-	"flag"
 	"log"
-	"os"
 	"testing"
 
 	"github.com/vvetta/electoral_system/internal/adapters/moto_parser"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/drift_history_repo"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/lock_repo"
 	"github.com/vvetta/electoral_system/internal/adapters/repository/moto_repo"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/salon_repo"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/repotest"
+	"github.com/vvetta/electoral_system/internal/adapters/repository/sync_run_repo"
 	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/domain"
 	"github.com/vvetta/electoral_system/internal/usecase"
 
-	"github.com/joho/godotenv"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var (
 	db *gorm.DB
-	integration = flag.Bool("integration", false, "run integration tests")
+	integration = repotest.Integration
 	mtRepo usecase.MotoRepo
+	slRepo usecase.SalonRepo
 	mtParser usecase.MotoParser
 	lg usecase.Logger
 	mtSVC usecase.MotoService
 )
 
 func TestMain(m *testing.M) {
-	flag.Parse()
-
-	_ = godotenv.Load(".env")
-
-	if *integration {
+	repotest.Main(m, func(testDB *gorm.DB) {
 		var err error
-
-		testDSN := getTestDSN()
-		db, err = gorm.Open(postgres.Open(testDSN), &gorm.Config{})
-		if err != nil {
-			log.Fatalf("error connetc to test db: %s", testDSN)			
-		}	
+		db = testDB
 
 		lg = logger.NewLogger()
 		mtRepo = motorepo.NewMotoRepo(db, lg)
-		mtParser = motoparser.NewMotoParser("https://mr-moto.ru/catalog/mototsikly/", "page-card__col", 100)
-
-		mtSVC = usecase.NewMotoService(lg, mtRepo, mtParser)		
-	}
-
-	code := m.Run()
-	os.Exit(code)
-}
-
-func getTestDSN() string {
-	var dsn string
-
-	DB_USER := os.Getenv("PG_TEST_USER")
-	DB_PASS := os.Getenv("PG_TEST_PASSWORD")
-	DB_HOST := os.Getenv("PG_TEST_HOST")
-	DB_PORT := os.Getenv("PG_TEST_PORT")
-	DB_NAME := os.Getenv("PG_TEST_DB_NAME")
+		slRepo = salonrepo.NewSalonRepo(db, lg)
+		mtParser, err = motoparser.NewMotoParser(
+			"https://mr-moto.ru/catalog/mototsikly/",
+			"../../configs/markup/mr-moto.json",
+			100,
+		)
+		if err != nil {
+			log.Fatalf("create moto parser error: %v", err)
+		}
 
-	dsn = "postgres://" + DB_USER + ":" + DB_PASS + "@" + DB_HOST + ":" + DB_PORT + "/" + DB_NAME + "?sslmode=disable"	
+		sources := usecase.NewSourceRegistry()
+		err = sources.Register(usecase.MotoSource{Name: "mr-moto", Enabled: true, Parser: mtParser})
+		if err != nil {
+			log.Fatalf("register source error: %v", err)
+		}
 
-	return dsn
+		mtSVC = usecase.NewMotoService(lg, mtRepo, slRepo, sources, domain.DefaultDriftPolicy(), drifthistoryrepo.NewDriftHistoryRepo(db, lg), lockrepo.NewLockRepo(db, lg), syncrunrepo.NewSyncRunRepo(db, lg))
+	})
 }
 
 func TestMotoService_ParseAndUpdateAllMoto(t *testing.T) {
@@ -72,15 +63,15 @@ func TestMotoService_ParseAndUpdateAllMoto(t *testing.T) {
 
 	ctx := context.Background()
 
-	motos, err := mtSVC.ParseAndUpdateAllMoto(ctx)
+	result, err := mtSVC.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{})
 	if err != nil {
 		t.Errorf("parse and update moto error: %v", err)
 	}
 
-	if len(motos) <= 1 {
-		t.Errorf("parser motos error. len < 1")
+	if result.Upserted <= 1 {
+		t.Errorf("parser motos error. upserted < 1")
 	}
 
-	log.Print(motos)
+	log.Print(result.Reports)
 }
 
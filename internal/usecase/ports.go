@@ -2,32 +2,140 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/vvetta/electoral_system/internal/domain"
 )
 
 type MotoParser interface {
-	GetAllMoto() ([]domain.Moto, error)
+	GetAllMoto(ctx context.Context, opts domain.ParseOptions) ([]domain.Moto, domain.ParseReport, error)
+	// StreamAllMoto отправляет мотоциклы в out по мере разбора, out не закрывает.
+	StreamAllMoto(ctx context.Context, opts domain.ParseOptions, out chan<- domain.Moto) (domain.ParseReport, error)
+}
+
+// SnapshotParser - парсер, который умеет разбирать сохраненные снимки страниц.
+type SnapshotParser interface {
+	// CheckSnapshot возвращает domain.SnapshotNotFound, если снимка id нет
+	CheckSnapshot(id string) error
 }
 
 type MotoRepo interface {
 	Create(ctx context.Context, moto domain.Moto) (domain.Moto, error)
 	Read(ctx context.Context, motoID uint) (domain.Moto, error)
 	Update(ctx context.Context, moto domain.Moto) (domain.Moto, error)
+	// UpsertMany пачкой обновляет мотоциклы по ExternalID в одной транзакции.
+	UpsertMany(ctx context.Context, motos []domain.Moto, opts domain.UpsertOptions) (domain.UpsertStats, error)
+	// DeactivateMissing снимает с показа активные объявления источника,
+	// которые не встречались в каталоге с seenSince. Возвращает их число.
+	DeactivateMissing(ctx context.Context, source string, seenSince time.Time) (int, error)
 	Delete(ctx context.Context, motoID uint) error
 
 	GetMotosByFilter(ctx context.Context, filter domain.MotoFilter) ([]domain.Moto, error)
+	// GetPriceHistory возвращает историю цены объявления от старых записей к новым.
+	GetPriceHistory(ctx context.Context, motoID uint) ([]domain.PricePoint, error)
+	// RekeyListings пересчитывает сохраненные ключи-хеши по domain.Moto.ListingKey.
+	RekeyListings(ctx context.Context) (domain.RekeyStats, error)
+}
+
+type SalonRepo interface {
+	// GetOrCreate возвращает салон с таким же именем, создавая его, если такого еще нет.
+	GetOrCreate(ctx context.Context, salon domain.Salon) (domain.Salon, error)
+	// Upsert создает или обновляет салон по имени вместе с адресом и контактами.
+	Upsert(ctx context.Context, salon domain.Salon) (domain.Salon, error)
+	Read(ctx context.Context, salonID uint) (domain.Salon, error)
+	List(ctx context.Context, filter domain.SalonFilter) ([]domain.Salon, error)
+}
+
+type JobRepo interface {
+	Create(ctx context.Context, job domain.ParseJob) error
+	Update(ctx context.Context, job domain.ParseJob) error
+	Read(ctx context.Context, jobID string) (domain.ParseJob, error)
+	// RunningOwners возвращает экземпляры приложения, у которых есть незавершенные задачи
+	RunningOwners(ctx context.Context) ([]string, error)
+	// InterruptOwner помечает прерванными незавершенные задачи экземпляра owner:
+	// он остановился, и их уже никто не выполняет. Возвращает их число.
+	InterruptOwner(ctx context.Context, owner string, at time.Time) (int, error)
+	// RequestCancel просит владельца остановить задачу; false - задача уже не идет
+	RequestCancel(ctx context.Context, jobID string) (bool, error)
+}
+
+type SyncRunRepo interface {
+	Create(ctx context.Context, run domain.SyncRun) (domain.SyncRun, error)
+	List(ctx context.Context, filter domain.SyncRunFilter) ([]domain.SyncRun, error)
+}
+
+/*
+DriftHistoryRepo хранит отчеты принятых обходов, с которыми сравнивается
+следующий обход (domain.DetectDrift). Лежит в базе, чтобы после перезапуска
+проверка дрейфа не начиналась с нуля.
+*/
+type DriftHistoryRepo interface {
+	Append(ctx context.Context, report domain.ParseReport) error
+	// Recent возвращает последние limit отчетов источника, новые первыми; limit <= 0 - все
+	Recent(ctx context.Context, source string, limit int) ([]domain.ParseReport, error)
+}
+
+// LockRepo - блокировки, общие для всех экземпляров приложения.
+type LockRepo interface {
+	// TryLock берет блокировку name, не дожидаясь ее. ok == false - ее держит
+	// кто-то другой. Взятую блокировку нужно отпустить вызовом unlock.
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
 }
 
 type MotoService interface {
 	GetMoto(ctx context.Context, motoID uint) (domain.Moto, error)
 	GetAllMoto(ctx context.Context) (domain.Moto, error)
-	ParseAndUpdateAllMoto(ctx context.Context) ([]domain.Moto, error)
+	ParseAndUpdateAllMoto(ctx context.Context, opts domain.ParseOptions) (domain.ParseResult, error)
+	// CheckParseOptions проверяет запуск до обхода: domain.SourceNotFound,
+	// domain.SnapshotNotFound или domain.InvalidInput
+	CheckParseOptions(ctx context.Context, opts domain.ParseOptions) error
+	// LockSync берет блокировку обхода, общую для всех экземпляров, и возвращает
+	// ее снятие; domain.SyncAlreadyRunning - обход уже идет здесь или в другом экземпляре
+	LockSync(ctx context.Context) (unlock func(), err error)
+	// ParseAndUpdateLocked - ParseAndUpdateAllMoto под блокировкой, уже взятой через LockSync
+	ParseAndUpdateLocked(ctx context.Context, opts domain.ParseOptions) (domain.ParseResult, error)
+	// Отчеты последнего запуска парсинга
+	LastParseReports(ctx context.Context) []domain.ParseReport
+	// Каноничные классы мотоциклов - общий словарь подбора и фильтра
+	GetMotoClasses(ctx context.Context) []domain.MotoClassInfo
+	// Здоровье источников по последним обходам, см. domain.DetectDrift
+	Health(ctx context.Context) []domain.SourceHealth
 	UpdateMoto(ctx context.Context, moto domain.Moto) (domain.Moto, error)
 	DeleteMoto(ctx context.Context, motoID uint) error
 
 	//TODO offset и limit делать не будут, но вообще он тут нужен!
 	GetMotosByFilter(ctx context.Context, filter domain.MotoFilter) ([]domain.Moto, error)
+	GetPriceHistory(ctx context.Context, motoID uint) ([]domain.PricePoint, error)
+	// Журнал обходов, новые записи первыми
+	GetSyncRuns(ctx context.Context, filter domain.SyncRunFilter) ([]domain.SyncRun, error)
+}
+
+type SalonService interface {
+	GetSalons(ctx context.Context, filter domain.SalonFilter) ([]domain.Salon, error)
+	GetSalon(ctx context.Context, salonID uint) (domain.Salon, error)
+	GetSalonMotos(ctx context.Context, salonID uint) ([]domain.Moto, error)
+	// ImportSalons дописывает адреса и контакты салонов из справочника.
+	ImportSalons(ctx context.Context, salons []domain.Salon) error
+}
+
+// JobService запускает парсинг в фоне и отдает его ход по id задачи.
+type JobService interface {
+	StartParse(ctx context.Context, opts domain.ParseOptions) (domain.ParseJob, error)
+	GetJob(ctx context.Context, jobID string) (domain.ParseJob, error)
+	// CancelJob останавливает обход; статус canceled задача получит, когда обход остановится
+	CancelJob(ctx context.Context, jobID string) (domain.ParseJob, error)
+	// Close останавливает задачи экземпляра и отпускает его блокировку владельца
+	Close()
+}
+
+// SyncScheduler запускает ParseAndUpdateAllMoto по расписанию, см. domain.SchedulePolicy.
+type SyncScheduler interface {
+	// Run крутит расписание, пока не отменят ctx
+	Run(ctx context.Context)
+	Status(ctx context.Context) domain.SchedulerStatus
+	SetEnabled(ctx context.Context, enabled bool) domain.SchedulerStatus
+	// RunNow запускает обход вне расписания, не дожидаясь его окончания
+	RunNow(ctx context.Context) error
 }
 
 type Logger interface {
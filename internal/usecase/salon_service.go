@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+type salonService struct {
+	log Logger
+	salonRepo SalonRepo
+	motoRepo MotoRepo
+}
+
+func NewSalonService(
+	log Logger,
+	salonRepo SalonRepo,
+	motoRepo MotoRepo,
+) SalonService {
+	return &salonService{
+		log: log,
+		salonRepo: salonRepo,
+		motoRepo: motoRepo,
+	}
+}
+
+func (s *salonService) GetSalons(
+	ctx context.Context,
+	filter domain.SalonFilter,
+) ([]domain.Salon, error) {
+	return s.salonRepo.List(ctx, filter)
+}
+
+func (s *salonService) GetSalon(
+	ctx context.Context,
+	salonID uint,
+) (domain.Salon, error) {
+	return s.salonRepo.Read(ctx, salonID)
+}
+
+func (s *salonService) GetSalonMotos(
+	ctx context.Context,
+	salonID uint,
+) ([]domain.Moto, error) {
+	motos, err := s.motoRepo.GetMotosByFilter(ctx, domain.MotoFilter{
+		SalonID: &salonID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	markPriceDrops(motos)
+	return motos, nil
+}
+
+func (s *salonService) ImportSalons(
+	ctx context.Context,
+	salons []domain.Salon,
+) error {
+	s.log.Debug("SalonService_ImportSalons: Start!", "count", len(salons))
+
+	var errs []error
+	for _, salon := range salons {
+		salon.Name = strings.Join(strings.Fields(salon.Name), " ")
+		if salon.Name == "" {
+			continue
+		}
+
+		if _, err := s.salonRepo.Upsert(ctx, salon); err != nil {
+			s.log.Error("SalonService_ImportSalons: upsert salon error", "salon", salon.Name, "err", err)
+			errs = append(errs, err)
+		}
+	}
+
+	s.log.Debug("SalonService_ImportSalons: End!")
+	return errors.Join(errs...)
+}
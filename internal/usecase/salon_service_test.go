@@ -0,0 +1,109 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+func TestSalonService_ImportSalons(t *testing.T) {
+	ctx := context.Background()
+	salons := &fakeSalonRepo{broken: "Сломанный салон"}
+	svc := usecase.NewSalonService(logger.NewLogger(), salons, &fakeMotoRepo{})
+
+	err := svc.ImportSalons(ctx, []domain.Salon{
+		{Name: "  Мото  Салон ", City: "Москва", Address: "ул. Тестовая, 1"},
+		{Name: " \t "},
+		{Name: "Сломанный салон"},
+		{Name: "Мото Салон", City: "Москва", Phone: "+7 000 000-00-00"},
+	})
+	if !errors.Is(err, domain.InternalError) {
+		t.Errorf("expected an error of the broken salon, got %v", err)
+	}
+
+	// Имя нормализуется так же, как при обходе, поэтому обе записи - один салон
+	if len(salons.salons) != 1 {
+		t.Fatalf("unexpected imported salons: %+v", salons.salons)
+	}
+	if salon := salons.salons[0]; salon.Name != "Мото Салон" || salon.Phone != "+7 000 000-00-00" {
+		t.Errorf("unexpected imported salon: %+v", salon)
+	}
+
+	found, err := svc.GetSalons(ctx, domain.SalonFilter{City: "москва"})
+	if err != nil || len(found) != 1 {
+		t.Errorf("salons by city: %+v, err: %v", found, err)
+	}
+	if _, err := svc.GetSalon(ctx, 42); !errors.Is(err, domain.RecordNotFound) {
+		t.Errorf("unknown salon: expected record not found, got %v", err)
+	}
+}
+
+func TestSalonService_CrawlResolvesSalons(t *testing.T) {
+	ctx := context.Background()
+	salons := &fakeSalonRepo{}
+	if _, err := salons.Upsert(ctx, domain.Salon{Name: "Мото Салон", City: "Москва"}); err != nil {
+		t.Fatalf("upsert salon error: %v", err)
+	}
+
+	sources := usecase.NewSourceRegistry()
+	parser := &fakeParser{cards: 4, locations: []string{" Мото  Салон", "Мото Салон ", "Новый салон", ""}}
+	if err := sources.Register(usecase.MotoSource{Name: "catalog", Enabled: true, Parser: parser}); err != nil {
+		t.Fatalf("register source error: %v", err)
+	}
+	repo := &fakeMotoRepo{}
+	motoSVC := usecase.NewMotoService(logger.NewLogger(), repo, salons, sources, domain.DefaultDriftPolicy(), &fakeDriftHistoryRepo{}, &fakeLockRepo{}, &fakeSyncRunRepo{})
+
+	if _, err := motoSVC.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); err != nil {
+		t.Fatalf("crawl error: %v", err)
+	}
+
+	// Строка "Мотосалон" с лишними пробелами находит салон из справочника,
+	// незнакомый салон создается, пустая - без салона
+	if len(salons.salons) != 2 || salons.salons[1].Name != "Новый салон" {
+		t.Fatalf("unexpected salons after crawl: %+v", salons.salons)
+	}
+	salonOf := make(map[string]uint)
+	for _, m := range repo.motos {
+		if m.SalonID != nil {
+			salonOf[m.SourceURL] = *m.SalonID
+		}
+	}
+	want := map[string]uint{"https://catalog.test/0": 1, "https://catalog.test/1": 1, "https://catalog.test/2": 2}
+	if len(salonOf) != len(want) {
+		t.Errorf("unexpected salons of motos: %v, want %v", salonOf, want)
+	}
+	for url, id := range want {
+		if salonOf[url] != id {
+			t.Errorf("salon of %s: %d, want %d", url, salonOf[url], id)
+		}
+	}
+}
+
+func TestSalonService_GetSalonMotos(t *testing.T) {
+	ctx := context.Background()
+	salonID, otherID := uint(1), uint(2)
+	changedAt := time.Now().Add(-time.Hour)
+	repo := &fakeMotoRepo{motos: []domain.Moto{
+		{ID: 1, SalonID: &salonID, Price: 900, PreviousPrice: 1000, PriceStatus: domain.PriceStatusFixed, PriceChangedAt: &changedAt},
+		{ID: 2, SalonID: &salonID, Price: 500, PriceStatus: domain.PriceStatusFixed},
+		{ID: 3, SalonID: &otherID, Price: 700, PriceStatus: domain.PriceStatusFixed},
+		{ID: 4, Price: 800, PriceStatus: domain.PriceStatusFixed},
+	}}
+	svc := usecase.NewSalonService(logger.NewLogger(), &fakeSalonRepo{}, repo)
+
+	motos, err := svc.GetSalonMotos(ctx, salonID)
+	if err != nil {
+		t.Fatalf("get salon motos error: %v", err)
+	}
+	if len(motos) != 2 || motos[0].ID != 1 || motos[1].ID != 2 {
+		t.Fatalf("unexpected salon motos: %+v", motos)
+	}
+	if !motos[0].RecentPriceDrop || motos[1].RecentPriceDrop {
+		t.Errorf("price drops are not marked: %+v", motos)
+	}
+}
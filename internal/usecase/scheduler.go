@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+/*
+syncScheduler обходит каталог по расписанию внутри приложения.
+Все запуски, и по расписанию, и "сейчас" через API, выполняются в одной
+горутине Run, поэтому друг на друга не накладываются. Обход, запущенный
+в это время вручную, отсекается самим сервисом (domain.SyncAlreadyRunning).
+*/
+type syncScheduler struct {
+	log Logger
+	svc MotoService
+	policy domain.SchedulePolicy
+	schedule domain.CronSchedule
+
+	runNow chan struct{}
+	wake chan struct{} // включили/выключили - пересчитать следующий запуск
+
+	mu sync.Mutex
+	status domain.SchedulerStatus
+	retry bool // повторять ли неудачный обход раньше расписания
+}
+
+func NewSyncScheduler(
+	log Logger,
+	svc MotoService,
+	policy domain.SchedulePolicy,
+	enabled bool,
+) (SyncScheduler, error) {
+	schedule, err := domain.ParseCron(policy.Cron)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w: cron %q never fires", domain.InvalidInput, policy.Cron)
+	}
+
+	return &syncScheduler{
+		log: log,
+		svc: svc,
+		policy: policy,
+		schedule: schedule,
+		runNow: make(chan struct{}, 1),
+		wake: make(chan struct{}, 1),
+		status: domain.SchedulerStatus{
+			Enabled: enabled,
+			Cron: schedule.String(),
+			Jitter: policy.Jitter.String(),
+		},
+	}, nil
+}
+
+func (s *syncScheduler) Run(ctx context.Context) {
+	s.log.Info("SyncScheduler_Run: Start!", "cron", s.policy.Cron, "jitter", s.policy.Jitter)
+
+	for {
+		var fire <-chan time.Time
+		var timer *time.Timer
+		if next, trigger := s.plan(time.Now()); next != nil {
+			s.log.Debug("SyncScheduler_Run: next run", "at", *next, "trigger", trigger)
+			timer = time.NewTimer(time.Until(*next))
+			fire = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			s.log.Info("SyncScheduler_Run: End!")
+			return
+		case <-s.wake:
+		case <-s.runNow:
+			s.run(ctx, domain.SyncTriggerManual)
+		case <-fire:
+			s.mu.Lock()
+			trigger := s.status.NextTrigger
+			s.mu.Unlock()
+			s.run(ctx, trigger)
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// plan возвращает время следующего запуска, считая его заново только после
+// запуска или переключения, чтобы случайная задержка не менялась на ходу.
+func (s *syncScheduler) plan(now time.Time) (*time.Time, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.status.Enabled {
+		s.status.NextRunAt = nil
+		s.status.NextTrigger = ""
+		return nil, ""
+	}
+	if s.status.NextRunAt != nil {
+		return s.status.NextRunAt, s.status.NextTrigger
+	}
+
+	next := s.schedule.Next(now)
+	if s.policy.Jitter > 0 {
+		next = next.Add(rand.N(s.policy.Jitter))
+	}
+	trigger := domain.SyncTriggerSchedule
+
+	if s.retry && s.status.ConsecutiveFailures > 0 {
+		if retryAt := now.Add(s.policy.Backoff(s.status.ConsecutiveFailures)); retryAt.Before(next) {
+			next = retryAt
+			trigger = domain.SyncTriggerRetry
+		}
+	}
+
+	s.status.NextRunAt = &next
+	s.status.NextTrigger = trigger
+	return &next, trigger
+}
+
+func (s *syncScheduler) run(ctx context.Context, trigger string) {
+	s.log.Info("SyncScheduler_Run: sync started", "trigger", trigger)
+
+	startedAt := time.Now()
+	s.mu.Lock()
+	s.status.Running = true
+	s.status.LastTrigger = trigger
+	s.status.LastStartedAt = &startedAt
+	s.mu.Unlock()
+
+	result, err := s.svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{})
+
+	finishedAt := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.Running = false
+	s.status.NextRunAt = nil
+	s.status.LastFinishedAt = &finishedAt
+	s.status.LastUpserted = result.Upserted
+	s.status.LastFailed = result.Failed
+	s.status.LastDeactivated = result.Deactivated
+
+	switch {
+	case errors.Is(err, domain.SyncAlreadyRunning):
+		// Каталог сейчас обходят вручную, это не неудача
+		s.status.LastError = err.Error()
+		s.log.Info("SyncScheduler_Run: sync skipped, another sync is running", "trigger", trigger)
+	case err != nil:
+		s.status.ConsecutiveFailures++
+		s.status.LastError = err.Error()
+		// Дрейф разметки повтором не исправить, ждем расписания
+		s.retry = !errors.Is(err, domain.SchemaDrift) && ctx.Err() == nil
+		s.log.Error("SyncScheduler_Run: sync failed", "trigger", trigger, "failures", s.status.ConsecutiveFailures, "err", err)
+	default:
+		s.status.ConsecutiveFailures = 0
+		s.status.LastError = ""
+		s.retry = false
+		s.log.Info("SyncScheduler_Run: sync finished", "trigger", trigger, "upserted", result.Upserted, "failed", result.Failed, "deactivated", result.Deactivated)
+	}
+}
+
+func (s *syncScheduler) Status(ctx context.Context) domain.SchedulerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *syncScheduler) SetEnabled(ctx context.Context, enabled bool) domain.SchedulerStatus {
+	s.mu.Lock()
+	if s.status.Enabled != enabled {
+		s.status.Enabled = enabled
+		s.status.NextRunAt = nil
+		s.status.NextTrigger = ""
+		s.log.Info("SyncScheduler_SetEnabled: switched", "enabled", enabled)
+	}
+	status := s.status
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return status
+}
+
+// RunNow ставит обход в очередь планировщика и сразу возвращается.
+// Работает и при выключенном расписании.
+func (s *syncScheduler) RunNow(ctx context.Context) error {
+	s.mu.Lock()
+	running := s.status.Running
+	s.mu.Unlock()
+	if running {
+		return domain.SyncAlreadyRunning
+	}
+
+	select {
+	case s.runNow <- struct{}{}:
+	default:
+		// Запуск уже в очереди
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", s, err)
+		}
+		return v
+	}
+
+	cases := []struct {
+		expr string
+		after string
+		want string
+	}{
+		{"0 */6 * * *", "2025-03-10 05:59", "2025-03-10 06:00"},
+		{"0 */6 * * *", "2025-03-10 06:00", "2025-03-10 12:00"},
+		{"30 3 * * *", "2025-03-10 04:00", "2025-03-11 03:30"},
+		{"*/15 9-10 * * *", "2025-03-10 10:50", "2025-03-11 09:00"},
+		{"0 12 * * 1-5", "2025-03-14 13:00", "2025-03-17 12:00"}, // пятница -> понедельник
+		{"0 0 * * 7", "2025-03-10 00:00", "2025-03-16 00:00"}, // 7 - воскресенье
+		{"0 0 1 * *", "2025-12-15 00:00", "2026-01-01 00:00"},
+		{"0 0 13 * 5", "2025-03-10 00:00", "2025-03-13 00:00"}, // 13 число или пятница
+		{"@hourly", "2025-03-10 05:20", "2025-03-10 06:00"},
+	}
+
+	for _, c := range cases {
+		schedule, err := domain.ParseCron(c.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", c.expr, err)
+		}
+		if got := schedule.Next(at(c.after)); !got.Equal(at(c.want)) {
+			t.Errorf("%q after %s: got %s, want %s", c.expr, c.after, got.Format("2006-01-02 15:04"), c.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := domain.ParseCron(expr); !errors.Is(err, domain.InvalidInput) {
+			t.Errorf("%q: expected invalid input, got %v", expr, err)
+		}
+	}
+}
+
+func TestSchedulePolicy_Backoff(t *testing.T) {
+	policy := domain.SchedulePolicy{BackoffMin: time.Minute, BackoffMax: 5 * time.Minute}
+
+	want := []time.Duration{0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for failures, w := range want {
+		if got := policy.Backoff(failures); got != w {
+			t.Errorf("backoff after %d failures: got %s, want %s", failures, got, w)
+		}
+	}
+}
+
+func TestSyncScheduler_RunNow(t *testing.T) {
+	svc := &fakeSyncService{started: make(chan struct{}), release: make(chan error)}
+	policy := domain.SchedulePolicy{Cron: "0 0 1 1 *", BackoffMin: time.Minute, BackoffMax: time.Hour}
+
+	scheduler, err := usecase.NewSyncScheduler(logger.NewLogger(), svc, policy, false)
+	if err != nil {
+		t.Fatalf("new scheduler: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Run(ctx)
+
+	if err := scheduler.RunNow(ctx); err != nil {
+		t.Fatalf("run now: %v", err)
+	}
+	<-svc.started
+
+	status := scheduler.Status(ctx)
+	if !status.Running || status.LastTrigger != domain.SyncTriggerManual {
+		t.Errorf("unexpected status while running: %+v", status)
+	}
+	if err := scheduler.RunNow(ctx); !errors.Is(err, domain.SyncAlreadyRunning) {
+		t.Errorf("overlapping run: expected already running, got %v", err)
+	}
+
+	svc.release <- errors.New("catalog is down")
+	status = waitScheduler(t, scheduler, func(s domain.SchedulerStatus) bool { return !s.Running })
+	if status.ConsecutiveFailures != 1 || status.LastError == "" || status.LastUpserted != 3 {
+		t.Errorf("failure not recorded: %+v", status)
+	}
+	if status.NextRunAt != nil {
+		t.Errorf("disabled scheduler has next run: %v", status.NextRunAt)
+	}
+
+	// После включения следующий запуск - повтор через BackoffMin, он раньше расписания
+	scheduler.SetEnabled(ctx, true)
+	status = waitScheduler(t, scheduler, func(s domain.SchedulerStatus) bool { return s.NextRunAt != nil })
+	if status.NextTrigger != domain.SyncTriggerRetry || time.Until(*status.NextRunAt) > time.Minute {
+		t.Errorf("expected retry within a minute: %+v", status)
+	}
+
+	scheduler.SetEnabled(ctx, false)
+	waitScheduler(t, scheduler, func(s domain.SchedulerStatus) bool { return s.NextRunAt == nil })
+}
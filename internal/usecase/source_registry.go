@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/vvetta/electoral_system/internal/domain"
+)
+
+// MotoSource - именованный источник мотоциклов со своим парсером.
+type MotoSource struct {
+	Name string
+	Enabled bool
+	Parser MotoParser
+}
+
+/*
+SourceRegistry хранит все известные источники.
+Сервис обходит либо все включенные источники, либо один выбранный по имени.
+*/
+type SourceRegistry struct {
+	mu sync.RWMutex
+	sources map[string]MotoSource
+}
+
+func NewSourceRegistry() *SourceRegistry {
+	return &SourceRegistry{
+		sources: make(map[string]MotoSource),
+	}
+}
+
+func (r *SourceRegistry) Register(source MotoSource) error {
+	if source.Name == "" {
+		return fmt.Errorf("%w: source name is empty", domain.InternalError)
+	}
+	if source.Parser == nil {
+		return fmt.Errorf("%w: source %q has no parser", domain.InternalError, source.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sources[source.Name]; ok {
+		return fmt.Errorf("%w: source %q", domain.RecordAlreadyExists, source.Name)
+	}
+	r.sources[source.Name] = source
+
+	return nil
+}
+
+func (r *SourceRegistry) Get(name string) (MotoSource, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	source, ok := r.sources[name]
+	if !ok {
+		return MotoSource{}, fmt.Errorf("%w: %q", domain.SourceNotFound, name)
+	}
+
+	return source, nil
+}
+
+// Enabled возвращает включенные источники, отсортированные по имени.
+func (r *SourceRegistry) Enabled() []MotoSource {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sources []MotoSource
+	for _, source := range r.sources {
+		if source.Enabled {
+			sources = append(sources, source)
+		}
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Name < sources[j].Name
+	})
+
+	return sources
+}
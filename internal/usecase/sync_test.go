@@ -0,0 +1,276 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vvetta/electoral_system/internal/adapters/logger"
+	"github.com/vvetta/electoral_system/internal/domain"
+	"github.com/vvetta/electoral_system/internal/usecase"
+)
+
+func newDriftTestService(t *testing.T, parser *fakeParser, action string) (usecase.MotoService, *fakeMotoRepo) {
+	t.Helper()
+	return newDriftTestServiceWithHistory(t, parser, action, &fakeDriftHistoryRepo{})
+}
+
+func newDriftTestServiceWithHistory(
+	t *testing.T,
+	parser *fakeParser,
+	action string,
+	history *fakeDriftHistoryRepo,
+) (usecase.MotoService, *fakeMotoRepo) {
+	t.Helper()
+
+	sources := usecase.NewSourceRegistry()
+	if err := sources.Register(usecase.MotoSource{Name: "catalog", Enabled: true, Parser: parser}); err != nil {
+		t.Fatalf("register source error: %v", err)
+	}
+
+	policy := domain.DefaultDriftPolicy()
+	policy.Action = action
+
+	repo := &fakeMotoRepo{}
+	return usecase.NewMotoService(logger.NewLogger(), repo, nil, sources, policy, history, &fakeLockRepo{}, &fakeSyncRunRepo{}), repo
+}
+
+func TestMotoService_SchemaDrift(t *testing.T) {
+	ctx := context.Background()
+	parser := &fakeParser{cards: 100, emptyPrice: 5}
+	svc, repo := newDriftTestService(t, parser, domain.DriftActionRefuse)
+
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); err != nil {
+		t.Fatalf("normal crawl error: %v", err)
+	}
+	if health := svc.Health(ctx); health[0].Status != domain.HealthOK || repo.upserted != 100 || repo.reconciled != 1 {
+		t.Fatalf("after normal crawl: health %+v, upserted %d, reconciled %d", health, repo.upserted, repo.reconciled)
+	}
+
+	// Разметка "уплыла": у всех пропала цена - видно уже по первой странице
+	parser.cards, parser.emptyPrice = 30, 30
+	_, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{})
+	if !errors.Is(err, domain.SchemaDrift) {
+		t.Fatalf("expected schema drift error, got %v", err)
+	}
+	if repo.upserted != 100 || repo.reconciled != 1 {
+		t.Errorf("drifted crawl was saved: upserted %d, reconciled %d", repo.upserted, repo.reconciled)
+	}
+
+	health := svc.Health(ctx)[0]
+	if health.Status != domain.HealthFailed || health.Committed || len(health.Issues) != 1 || health.Issues[0].Check != domain.DriftCheckEmptyField {
+		t.Errorf("unexpected health after drift: %+v", health)
+	}
+	if reports := svc.LastParseReports(ctx); len(reports) != 1 || len(reports[0].Drift) != 1 {
+		t.Errorf("drift is not in the report: %+v", reports)
+	}
+
+	// Карточек втрое меньше - это видно только в конце, когда они уже записаны,
+	// но остальное с показа не снимается
+	parser.emptyPrice = 0
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); !errors.Is(err, domain.SchemaDrift) {
+		t.Fatalf("expected schema drift error, got %v", err)
+	}
+	if health := svc.Health(ctx)[0]; health.Status != domain.HealthFailed || !health.Committed || repo.upserted != 130 || repo.reconciled != 1 {
+		t.Errorf("after late drift: health %+v, upserted %d, reconciled %d", health, repo.upserted, repo.reconciled)
+	}
+
+	// Force записывает обход и делает его новой нормой
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{Force: true}); err != nil {
+		t.Fatalf("forced crawl error: %v", err)
+	}
+	if health := svc.Health(ctx)[0]; health.Status != domain.HealthDegraded || repo.upserted != 160 || repo.reconciled != 2 {
+		t.Errorf("after forced crawl: health %+v, upserted %d, reconciled %d", health, repo.upserted, repo.reconciled)
+	}
+}
+
+func TestMotoService_SchemaDrift_HistorySurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	history := &fakeDriftHistoryRepo{}
+
+	svc, _ := newDriftTestServiceWithHistory(t, &fakeParser{cards: 100}, domain.DriftActionRefuse, history)
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); err != nil {
+		t.Fatalf("normal crawl error: %v", err)
+	}
+
+	// Новый экземпляр сервиса сравнивает с теми же прошлыми обходами
+	restarted, repo := newDriftTestServiceWithHistory(t, &fakeParser{cards: 100, emptyPrice: 100}, domain.DriftActionRefuse, history)
+	if _, err := restarted.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); !errors.Is(err, domain.SchemaDrift) {
+		t.Fatalf("expected schema drift after restart, got %v", err)
+	}
+	if repo.upserted != 0 {
+		t.Errorf("drifted crawl was saved after restart: %d", repo.upserted)
+	}
+}
+
+func TestMotoService_SchemaDrift_Flag(t *testing.T) {
+	ctx := context.Background()
+	parser := &fakeParser{cards: 0}
+	svc, repo := newDriftTestService(t, parser, domain.DriftActionFlag)
+
+	if health := svc.Health(ctx)[0]; health.Status != domain.HealthUnknown {
+		t.Errorf("health before crawls = %s, want unknown", health.Status)
+	}
+
+	// Ноль карточек - дрейф даже без истории, но в режиме flag обход не падает
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); err != nil {
+		t.Fatalf("flagged crawl error: %v", err)
+	}
+
+	health := svc.Health(ctx)[0]
+	if health.Status != domain.HealthDegraded || !health.Committed || health.Issues[0].Check != domain.DriftCheckCards {
+		t.Errorf("unexpected health: %+v", health)
+	}
+	// Ноль карточек с дрейфом - не повод снять с показа весь каталог
+	if repo.upserted != 0 || repo.reconciled != 0 {
+		t.Errorf("upserted %d, reconciled %d, want 0", repo.upserted, repo.reconciled)
+	}
+}
+
+func TestMotoService_SnapshotReplay(t *testing.T) {
+	ctx := context.Background()
+	history := &fakeDriftHistoryRepo{}
+	svc, repo := newDriftTestServiceWithHistory(t, &fakeParser{cards: 50, emptyPrice: 50}, domain.DriftActionRefuse, history)
+	history.reports = []domain.ParseReport{{Source: "catalog", CardsFound: 50, CardsParsed: 50}}
+
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{Source: "catalog", Snapshot: "missing"}); !errors.Is(err, domain.SnapshotNotFound) {
+		t.Errorf("expected snapshot not found, got %v", err)
+	}
+
+	// Без commit снимок только разбирается: дрейф в отчете, в базе и здоровье ничего не меняется
+	result, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{Source: "catalog", Snapshot: "20250101T120000.000Z"})
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	if repo.upserted != 0 || result.Upserted != 0 || len(result.Reports) != 1 || len(result.Reports[0].Drift) != 1 {
+		t.Errorf("dry run: upserted %d, result %+v", repo.upserted, result)
+	}
+	if health := svc.Health(ctx)[0]; health.Status != domain.HealthUnknown {
+		t.Errorf("dry run changed health: %+v", health)
+	}
+
+	// С commit снимок пишется, но объявления не отмечаются виденными и не сверяются
+	result, err = svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{Source: "catalog", Snapshot: "20250101T120000.000Z", Commit: true, Force: true})
+	if err != nil {
+		t.Fatalf("commit replay error: %v", err)
+	}
+	if repo.upserted != 50 || repo.reconciled != 0 {
+		t.Errorf("commit replay: upserted %d, reconciled %d", repo.upserted, repo.reconciled)
+	}
+	if len(history.reports) != 1 {
+		t.Errorf("snapshot replay became a drift baseline: %d reports", len(history.reports))
+	}
+}
+
+func TestMotoService_TruncatedCrawlDoesNotReconcile(t *testing.T) {
+	ctx := context.Background()
+	parser := &fakeParser{cards: 100, truncated: true}
+	svc, repo := newDriftTestService(t, parser, domain.DriftActionRefuse)
+
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); err != nil {
+		t.Fatalf("truncated crawl error: %v", err)
+	}
+	// Что дальше max_page_count - неизвестно, снимать с показа нечего
+	if repo.upserted != 100 || repo.reconciled != 0 {
+		t.Errorf("upserted %d, reconciled %d, want 100 and 0", repo.upserted, repo.reconciled)
+	}
+
+	parser.truncated = false
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); err != nil {
+		t.Fatalf("full crawl error: %v", err)
+	}
+	if repo.reconciled != 1 {
+		t.Errorf("full crawl reconciled %d times, want 1", repo.reconciled)
+	}
+}
+
+func TestMotoService_BatchErrorRetriesRows(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newDriftTestService(t, &fakeParser{cards: 120}, domain.DriftActionFlag)
+	repo.broken = "https://catalog.test/7"
+
+	result, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{})
+	if err != nil {
+		t.Fatalf("parse and update error: %v", err)
+	}
+	// Пачка с плохой строкой дописана по одной, потеряна только она
+	if result.Failed != 1 || result.Upserted != 119 || repo.upserted != 119 {
+		t.Errorf("failed %d, upserted %d (repo %d), want 1 and 119", result.Failed, result.Upserted, repo.upserted)
+	}
+}
+
+func TestMotoService_SyncLockedByAnotherInstance(t *testing.T) {
+	ctx := context.Background()
+
+	sources := usecase.NewSourceRegistry()
+	if err := sources.Register(usecase.MotoSource{Name: "catalog", Enabled: true, Parser: &fakeParser{cards: 10}}); err != nil {
+		t.Fatalf("register source error: %v", err)
+	}
+	lock := &fakeLockRepo{held: true}
+	repo := &fakeMotoRepo{}
+	svc := usecase.NewMotoService(logger.NewLogger(), repo, nil, sources, domain.DefaultDriftPolicy(), &fakeDriftHistoryRepo{}, lock, &fakeSyncRunRepo{})
+
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); !errors.Is(err, domain.SyncAlreadyRunning) {
+		t.Fatalf("expected already running, got %v", err)
+	}
+	if repo.upserted != 0 {
+		t.Errorf("motos written without the lock: %d", repo.upserted)
+	}
+
+	lock.held = false
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); err != nil {
+		t.Fatalf("crawl after lock release error: %v", err)
+	}
+	if repo.upserted != 10 || lock.held {
+		t.Errorf("upserted %d, lock held after crawl: %v", repo.upserted, lock.held)
+	}
+}
+
+func TestMotoService_RecordsSyncRuns(t *testing.T) {
+	ctx := context.Background()
+
+	parser := &fakeParser{cards: 20}
+	sources := usecase.NewSourceRegistry()
+	if err := sources.Register(usecase.MotoSource{Name: "catalog", Enabled: true, Parser: parser}); err != nil {
+		t.Fatalf("register source error: %v", err)
+	}
+	runs := &fakeSyncRunRepo{}
+	svc := usecase.NewMotoService(logger.NewLogger(), &fakeMotoRepo{}, nil, sources, domain.DefaultDriftPolicy(), &fakeDriftHistoryRepo{}, &fakeLockRepo{}, runs)
+
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); err != nil {
+		t.Fatalf("crawl error: %v", err)
+	}
+
+	// Разметка "уплыла" - обход не записан, но в журнал попадает с ошибкой
+	parser.cards = 0
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{}); !errors.Is(err, domain.SchemaDrift) {
+		t.Fatalf("expected schema drift, got %v", err)
+	}
+
+	if len(runs.runs) != 2 {
+		t.Fatalf("expected 2 sync runs, got %d", len(runs.runs))
+	}
+	ok, failed := runs.runs[0], runs.runs[1]
+	if ok.Source != "catalog" || ok.Kind != domain.SyncRunKindCrawl || ok.CardsFound != 20 || ok.Inserted != 20 || !ok.Succeeded() || ok.FinishedAt.Before(ok.StartedAt) {
+		t.Errorf("unexpected successful run: %+v", ok)
+	}
+	if failed.Succeeded() || failed.Inserted != 0 {
+		t.Errorf("unexpected failed run: %+v", failed)
+	}
+
+	// Разбор снимка в журнале отличается от обхода сайта
+	parser.cards = 20
+	if _, err := svc.ParseAndUpdateAllMoto(ctx, domain.ParseOptions{Source: "catalog", Snapshot: "old"}); err != nil {
+		t.Fatalf("snapshot replay error: %v", err)
+	}
+	if snapshot := runs.runs[len(runs.runs)-1]; snapshot.Kind != domain.SyncRunKindSnapshot {
+		t.Errorf("unexpected snapshot run kind: %q", snapshot.Kind)
+	}
+
+	if _, err := svc.GetSyncRuns(ctx, domain.SyncRunFilter{Status: "broken"}); !errors.Is(err, domain.InvalidInput) {
+		t.Errorf("expected invalid input for unknown status, got %v", err)
+	}
+	if _, err := svc.GetSyncRuns(ctx, domain.SyncRunFilter{Kind: "replay"}); !errors.Is(err, domain.InvalidInput) {
+		t.Errorf("expected invalid input for unknown kind, got %v", err)
+	}
+}
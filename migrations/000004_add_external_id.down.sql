@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_motos_external_id;
+ALTER TABLE motos DROP COLUMN external_id;
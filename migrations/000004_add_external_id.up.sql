@@ -0,0 +1,2 @@
+ALTER TABLE motos ADD COLUMN external_id VARCHAR(512);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_motos_external_id ON motos (external_id);
@@ -0,0 +1,4 @@
+ALTER TABLE motos
+    DROP COLUMN dealer_url,
+    DROP COLUMN image_url,
+    DROP COLUMN source_url;
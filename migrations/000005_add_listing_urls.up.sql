@@ -0,0 +1,4 @@
+ALTER TABLE motos
+    ADD COLUMN source_url VARCHAR(512) NOT NULL DEFAULT '',
+    ADD COLUMN image_url VARCHAR(512) NOT NULL DEFAULT '',
+    ADD COLUMN dealer_url VARCHAR(512) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_motos_source;
+ALTER TABLE motos DROP COLUMN source;
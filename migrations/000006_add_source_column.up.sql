@@ -0,0 +1,2 @@
+ALTER TABLE motos ADD COLUMN source VARCHAR(100) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_motos_source ON motos (source);
@@ -0,0 +1,8 @@
+ALTER TABLE motos
+    DROP COLUMN details_parsed_at,
+    DROP COLUMN description,
+    DROP COLUMN abs,
+    DROP COLUMN transmission,
+    DROP COLUMN color,
+    DROP COLUMN weight,
+    DROP COLUMN horsepower;
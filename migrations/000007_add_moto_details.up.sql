@@ -0,0 +1,8 @@
+ALTER TABLE motos
+    ADD COLUMN horsepower INT NOT NULL DEFAULT 0,
+    ADD COLUMN weight INT NOT NULL DEFAULT 0,
+    ADD COLUMN color VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN transmission VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN abs BOOLEAN,
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN details_parsed_at TIMESTAMPTZ;
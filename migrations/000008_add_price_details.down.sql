@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_motos_price_status;
+
+ALTER TABLE motos
+    DROP COLUMN price_status,
+    DROP COLUMN price_currency,
+    DROP COLUMN old_price,
+    ALTER COLUMN price TYPE INT;
@@ -0,0 +1,13 @@
+-- Цены в других валютах и у дорогих объявлений не помещаются в INT
+ALTER TABLE motos
+    ALTER COLUMN price TYPE BIGINT,
+    ADD COLUMN old_price BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN price_currency VARCHAR(3) NOT NULL DEFAULT '',
+    ADD COLUMN price_status VARCHAR(20) NOT NULL DEFAULT 'unknown';
+
+-- Раньше цена 0 означала и "по запросу", и "не разобрали"
+UPDATE motos
+SET price_status = CASE WHEN price > 0 THEN 'fixed' ELSE 'unknown' END,
+    price_currency = CASE WHEN price > 0 THEN 'RUB' ELSE '' END;
+
+CREATE INDEX idx_motos_price_status ON motos (price_status);
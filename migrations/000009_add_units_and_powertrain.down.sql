@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_motos_powertrain;
+
+ALTER TABLE motos
+    DROP COLUMN motor_power_kw,
+    DROP COLUMN powertrain,
+    DROP COLUMN engine_size_unit,
+    DROP COLUMN mileage_unit,
+    DROP COLUMN mileage_original;
@@ -0,0 +1,12 @@
+ALTER TABLE motos
+    ADD COLUMN mileage_original INT NOT NULL DEFAULT 0,
+    ADD COLUMN mileage_unit VARCHAR(10) NOT NULL DEFAULT '',
+    ADD COLUMN engine_size_unit VARCHAR(10) NOT NULL DEFAULT '',
+    ADD COLUMN powertrain VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN motor_power_kw DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Старые записи парсились без единиц: пробег считался в км, объем в cc
+UPDATE motos SET mileage_original = mileage, mileage_unit = 'km' WHERE mileage > 0;
+UPDATE motos SET engine_size_unit = 'cc', powertrain = 'ice' WHERE engine_size > 0;
+
+CREATE INDEX idx_motos_powertrain ON motos (powertrain);
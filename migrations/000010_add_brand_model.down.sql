@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_motos_brand_model;
+
+ALTER TABLE motos
+    DROP COLUMN trim,
+    DROP COLUMN model,
+    DROP COLUMN brand;
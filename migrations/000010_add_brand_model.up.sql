@@ -0,0 +1,7 @@
+ALTER TABLE motos
+    ADD COLUMN brand VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN model VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN trim VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Фильтр сравнивает марку и модель без учета регистра
+CREATE INDEX idx_motos_brand_model ON motos (LOWER(brand), LOWER(model));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_motos_salon_id;
+
+ALTER TABLE motos DROP COLUMN salon_id;
+
+DROP TABLE IF EXISTS salons;
@@ -0,0 +1,38 @@
+CREATE TABLE salons (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    address VARCHAR(512) NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION,
+    longitude DOUBLE PRECISION,
+    phone VARCHAR(50) NOT NULL DEFAULT '',
+    url VARCHAR(512) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_salons_name ON salons (name);
+CREATE INDEX idx_salons_city ON salons (city);
+
+ALTER TABLE motos
+    ADD COLUMN salon_id BIGINT REFERENCES salons (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_motos_salon_id ON motos (salon_id);
+
+-- Салоны из уже спарсенных объявлений. Имя салона нормализуется так же,
+-- как при обходе (resolveSalon, strings.Fields): пробелы по краям убираются,
+-- внутри схлопываются. Класс - пробелы Go unicode.IsSpace, в т.ч. неразрывные
+INSERT INTO salons (name, url)
+SELECT name, MAX(dealer_url)
+FROM (
+    SELECT btrim(regexp_replace(location, '[[:space:]\u0085\u00a0\u1680\u2000-\u200a\u2028\u2029\u202f\u205f\u3000]+', ' ', 'g')) AS name,
+        dealer_url
+    FROM motos
+) AS parsed
+WHERE name <> ''
+GROUP BY name
+ON CONFLICT (name) DO NOTHING;
+
+UPDATE motos SET salon_id = salons.id
+FROM salons
+WHERE salons.name = btrim(regexp_replace(motos.location, '[[:space:]\u0085\u00a0\u1680\u2000-\u200a\u2028\u2029\u202f\u205f\u3000]+', ' ', 'g'));
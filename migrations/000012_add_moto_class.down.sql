@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_motos_moto_class;
+
+ALTER TABLE motos
+    DROP COLUMN moto_class;
@@ -0,0 +1,7 @@
+ALTER TABLE motos
+    ADD COLUMN moto_class VARCHAR(30) NOT NULL DEFAULT '';
+
+-- Старые записи остаются без класса: его проставит следующий обход по
+-- configs/moto_classes.json, копировать таблицу синонимов сюда незачем
+
+CREATE INDEX idx_motos_moto_class ON motos (moto_class);
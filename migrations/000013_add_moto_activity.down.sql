@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_motos_source_last_seen_at;
+DROP INDEX IF EXISTS idx_motos_deactivated_at;
+
+ALTER TABLE motos
+    DROP COLUMN deactivated_at,
+    DROP COLUMN last_seen_at;
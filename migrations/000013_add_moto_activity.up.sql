@@ -0,0 +1,10 @@
+ALTER TABLE motos
+    ADD COLUMN last_seen_at TIMESTAMPTZ,
+    ADD COLUMN deactivated_at TIMESTAMPTZ;
+
+-- До этой миграции объявления обновлялись только при обходе,
+-- так что время обновления - лучшее, что известно о последнем появлении
+UPDATE motos SET last_seen_at = updated_at;
+
+CREATE INDEX idx_motos_deactivated_at ON motos (deactivated_at);
+CREATE INDEX idx_motos_source_last_seen_at ON motos (source, last_seen_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_motos_price_changed_at;
+
+ALTER TABLE motos
+    DROP COLUMN price_changed_at,
+    DROP COLUMN previous_price;
+
+DROP TABLE IF EXISTS moto_price_history;
@@ -0,0 +1,23 @@
+CREATE TABLE moto_price_history (
+    id BIGSERIAL PRIMARY KEY,
+    moto_id BIGINT NOT NULL REFERENCES motos (id) ON DELETE CASCADE,
+    price BIGINT NOT NULL,
+    old_price BIGINT NOT NULL DEFAULT 0,
+    price_currency VARCHAR(3) NOT NULL DEFAULT '',
+    price_status VARCHAR(20) NOT NULL DEFAULT '',
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_moto_price_history_moto_id_recorded_at ON moto_price_history (moto_id, recorded_at);
+
+ALTER TABLE motos
+    ADD COLUMN previous_price BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN price_changed_at TIMESTAMPTZ;
+
+CREATE INDEX idx_motos_price_changed_at ON motos (price_changed_at);
+
+-- Истории до этой миграции нет: первой точкой становится текущая цена
+INSERT INTO moto_price_history (moto_id, price, old_price, price_currency, price_status, recorded_at)
+SELECT id, price, old_price, price_currency, price_status, created_at
+FROM motos
+WHERE deleted_at IS NULL;
@@ -0,0 +1,18 @@
+CREATE TABLE parse_jobs (
+    id VARCHAR(64) PRIMARY KEY,
+    status VARCHAR(20) NOT NULL,
+    source VARCHAR(100) NOT NULL DEFAULT '',
+    with_details BOOLEAN NOT NULL DEFAULT FALSE,
+    snapshot VARCHAR(100) NOT NULL DEFAULT '',
+    force BOOLEAN NOT NULL DEFAULT FALSE,
+    pages_fetched INT NOT NULL DEFAULT 0,
+    upserted INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    deactivated INT NOT NULL DEFAULT 0,
+    errors TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_parse_jobs_status ON parse_jobs (status);
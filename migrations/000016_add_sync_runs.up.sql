@@ -0,0 +1,16 @@
+CREATE TABLE sync_runs (
+    id BIGSERIAL PRIMARY KEY,
+    source VARCHAR(100) NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    pages INT NOT NULL DEFAULT 0,
+    cards_found INT NOT NULL DEFAULT 0,
+    inserted INT NOT NULL DEFAULT 0,
+    updated INT NOT NULL DEFAULT 0,
+    deactivated INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_sync_runs_started_at ON sync_runs (started_at);
+CREATE INDEX idx_sync_runs_source_started_at ON sync_runs (source, started_at);
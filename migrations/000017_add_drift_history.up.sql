@@ -0,0 +1,8 @@
+CREATE TABLE drift_history (
+    id BIGSERIAL PRIMARY KEY,
+    source VARCHAR(100) NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    report TEXT NOT NULL
+);
+
+CREATE INDEX idx_drift_history_source_finished_at ON drift_history (source, finished_at);
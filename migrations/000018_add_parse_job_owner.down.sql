@@ -0,0 +1,3 @@
+ALTER TABLE parse_jobs
+    DROP COLUMN cancel_requested,
+    DROP COLUMN owner;
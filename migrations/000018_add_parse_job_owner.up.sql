@@ -0,0 +1,3 @@
+ALTER TABLE parse_jobs
+    ADD COLUMN owner VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN cancel_requested BOOLEAN NOT NULL DEFAULT FALSE;
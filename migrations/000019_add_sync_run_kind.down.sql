@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_sync_runs_kind_started_at;
+
+ALTER TABLE sync_runs
+    DROP COLUMN kind;
@@ -0,0 +1,6 @@
+-- Что это был за обход: живой обход сайта, разбор снимка страниц или файл дилера.
+-- Старые записи считаем обходами сайта: различить их уже нечем
+ALTER TABLE sync_runs
+    ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'crawl';
+
+CREATE INDEX idx_sync_runs_kind_started_at ON sync_runs (kind, started_at);
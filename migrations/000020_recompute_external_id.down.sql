@@ -0,0 +1,8 @@
+-- Возвращаем ключи, которые пересчитал cmd/rekey. Строки-дубли, которые он
+-- слил с более свежими, не восстанавливаются: их история цен уже перенесена
+UPDATE motos
+SET external_id = previous_external_id
+WHERE previous_external_id IS NOT NULL;
+
+ALTER TABLE motos
+    DROP COLUMN previous_external_id;
@@ -0,0 +1,7 @@
+-- Ключ-хеш объявления теперь считается без пробега (domain.Moto.ListingKey).
+-- Сами ключи пересчитывает Go-команда cmd/rekey (make rekey-listings): в SQL
+-- trim/lower ведут себя не как strings.TrimSpace/ToLower (NBSP, локаль базы),
+-- и ключи разошлись бы с теми, что посчитает обход.
+-- Старый ключ сохраняется здесь, чтобы миграцию можно было откатить
+ALTER TABLE motos
+    ADD COLUMN previous_external_id VARCHAR(512);